@@ -1,21 +1,25 @@
 package lufthansa
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 	"unsafe"
 
+	"github.com/tmaxmax/lufthansaapi/internal/singleflight"
 	"github.com/tmaxmax/lufthansaapi/internal/util"
 
-	"golang.org/x/time/rate"
-
+	"github.com/tmaxmax/lufthansaapi/pkg/circuitbreaker"
 	"github.com/tmaxmax/lufthansaapi/pkg/ratelimithttp"
 )
 
@@ -71,14 +75,24 @@ func (t *token) String() string {
 // Do not create a new API struct per HTTP request, if your application is a HTTP server, use the same struct globally!
 // Not doing so will mess rate management and authentication, leading to undesired errors!
 type API struct {
-	client       *ratelimithttp.Client
-	clientID     string
-	clientSecret string
-	token        *token
-	tokenMu      sync.RWMutex
-	addr         *API
+	client        *ratelimithttp.Client
+	clientID      string
+	clientSecret  string
+	token         *token
+	tokenMu       sync.RWMutex
+	resolver      *Resolver
+	resolverOnce  sync.Once
+	cache         *responseCache
+	quotaStore    ratelimithttp.Store
+	breaker       *circuitbreaker.Breaker
+	transportOpts []ratelimithttp.Option
+	flight        singleflight.Group
+	addr          *API
 }
 
+// Option configures an API. Pass options to NewAPI.
+type Option func(*API)
+
 //go:nosplit
 //go:nocheckptr
 func noescape(p unsafe.Pointer) unsafe.Pointer {
@@ -129,13 +143,63 @@ func (a *API) refreshToken(ctx context.Context) error {
 	return nil
 }
 
-// fetch function returns the API response from the provided URL as an io.ReadCloser. The caller goroutine shall close the reader.
-func (a *API) fetch(ctx context.Context, url string) (io.ReadCloser, error) {
+// validators holds the ETag and Last-Modified headers of a response, which are used to make conditional
+// requests for the same URL.
+type validators struct {
+	key          string
+	etag         string
+	lastModified string
+}
+
+// matches reports whether there are validators stored for the given URL.
+func (v *validators) matches(url string) bool {
+	if v.etag == "" && v.lastModified == "" {
+		return false
+	}
+	key, _, err := cacheKey(url)
+	return err == nil && key == v.key
+}
+
+func (v *validators) set(url string, h http.Header) {
+	v.key, _, _ = cacheKey(url)
+	v.etag = h.Get("ETag")
+	v.lastModified = h.Get("Last-Modified")
+}
+
+// request does an authenticated, rate limited request to the given URL. The response is returned only if the API
+// didn't respond with an error, which is decoded and returned instead. The caller goroutine shall close the body.
+func (a *API) request(ctx context.Context, method, url string, body io.Reader) (*http.Response, error) {
+	return a.requestConditional(ctx, method, url, body, nil)
+}
+
+// requestConditional does the same as request. If v is not nil, the request is made conditional on the stored
+// validators, if they belong to the URL, and on success v is overwritten with the validators of the response.
+// If the resource wasn't modified, errNotModified is returned. The request goes through the circuit breaker, which
+// is told the outcome once the response headers arrive, and the failures while reading the body afterwards.
+func (a *API) requestConditional(ctx context.Context, method, url string, body io.Reader, v *validators) (*http.Response, error) {
 	a.copyCheck()
+	if a.breaker == nil {
+		return a.send(ctx, method, url, body, v)
+	}
+	done, err := a.breaker.Allow()
+	if err != nil {
+		return nil, err
+	}
+	res, err := a.send(ctx, method, url, body, v)
+	done(breakerOutcome(ctx, err))
+	if err != nil {
+		return nil, err
+	}
+	res.Body = &breakerBody{ReadCloser: res.Body, ctx: ctx, breaker: a.breaker}
+	return res, nil
+}
+
+// send does the request for requestConditional.
+func (a *API) send(ctx context.Context, method, url string, body io.Reader, v *validators) (*http.Response, error) {
 	if err := a.refreshToken(ctx); err != nil {
 		return nil, err
 	}
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
 	if err != nil {
 		return nil, err
 	}
@@ -143,6 +207,14 @@ func (a *API) fetch(ctx context.Context, url string) (io.ReadCloser, error) {
 	req.Header.Add("Accept", "application/xml")
 	req.Header.Add("Accept", "*/*")
 	req.Header.Add("Authorization", a.token.String())
+	if v != nil && v.matches(url) {
+		if v.etag != "" {
+			req.Header.Set("If-None-Match", v.etag)
+		}
+		if v.lastModified != "" {
+			req.Header.Set("If-Modified-Since", v.lastModified)
+		}
+	}
 	res, err := a.client.Do(req)
 	if err != nil {
 		return nil, err
@@ -150,22 +222,188 @@ func (a *API) fetch(ctx context.Context, url string) (io.ReadCloser, error) {
 	if err = decodeErrors(res); err != nil {
 		return nil, err
 	}
-	return res.Body, nil
+	if v != nil {
+		v.set(url, res.Header)
+	}
+	return res, nil
+}
+
+// sharedResponse is the response of a GET request shared by concurrent callers. It must not be modified.
+type sharedResponse struct {
+	data   []byte
+	header http.Header
+}
+
+func (s *sharedResponse) body() io.ReadCloser {
+	return ioutil.NopCloser(bytes.NewReader(s.data))
+}
+
+// fetchShared does a GET request to the provided URL. Concurrent calls for the same URL and with the same request
+// priority share a single request, which is canceled only if all the callers' contexts are done. Calls with different
+// priorities aren't shared, so that a request isn't held back by the lower priority of the caller that started it.
+func (a *API) fetchShared(ctx context.Context, url string) (*sharedResponse, error) {
+	key, _, err := cacheKey(url)
+	if err != nil {
+		key = url
+	}
+	key = strconv.Itoa(int(ratelimithttp.PriorityFrom(ctx))) + " " + key
+	v, err, _ := a.flight.DoContext(ctx, key, func(ctx context.Context) (interface{}, error) {
+		res, err := a.request(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return nil, err
+		}
+		data, err := util.ReadAll(res.Body)
+		if err != nil {
+			return nil, err
+		}
+		return &sharedResponse{data: data, header: res.Header}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.(*sharedResponse), nil
+}
+
+// fetch function returns the API response from the provided URL as an io.ReadCloser. The response is served from
+// the cache, if possible, and identical in-flight requests are coalesced. The caller goroutine shall close the reader.
+func (a *API) fetch(ctx context.Context, url string) (io.ReadCloser, error) {
+	return a.cache.fetchCached(ctx, url, func() (io.ReadCloser, error) {
+		res, err := a.fetchShared(ctx, url)
+		if err != nil {
+			return nil, err
+		}
+		return res.body(), nil
+	})
+}
+
+// fetchValidated does the same as fetch, storing the validators of the response into v. If v holds validators for
+// the URL or fresh is true, the cache is bypassed: a request is done, conditional if there are validators, and its
+// response is stored back into the cache. errNotModified is returned if the resource didn't change. The validators
+// of responses served from the cache are unknown, so v is cleared in that case.
+func (a *API) fetchValidated(ctx context.Context, url string, v *validators, fresh bool) (io.ReadCloser, error) {
+	if fresh || v.matches(url) {
+		res, err := a.requestConditional(ctx, http.MethodGet, url, nil, v)
+		if err != nil {
+			return nil, err
+		}
+		data, err := util.ReadAll(res.Body)
+		if err != nil {
+			return nil, err
+		}
+		a.cache.store(ctx, url, data)
+		return ioutil.NopCloser(bytes.NewReader(data)), nil
+	}
+	*v = validators{}
+	return a.cache.fetchCached(ctx, url, func() (io.ReadCloser, error) {
+		res, err := a.fetchShared(ctx, url)
+		if err != nil {
+			return nil, err
+		}
+		v.set(url, res.header)
+		return res.body(), nil
+	})
+}
+
+// ErrForeignURL is returned by API.Raw and API.Do when given an absolute URL that doesn't point to the
+// Lufthansa API, so that the access token isn't sent to other hosts.
+var ErrForeignURL = errors.New("lufthansa: URL is outside of the Lufthansa API")
+
+// ErrUnsupportedMethod is returned by API.Do when given a method of requests that should have a body.
+var ErrUnsupportedMethod = errors.New("lufthansa: unsupported request method")
+
+// resolveURL builds the request URL from a path relative to the API root (for example
+// "/operations/flightstatus/LH400/2020-08-26") and the query parameters. Absolute URLs are accepted only if
+// they point to the API, otherwise ErrForeignURL is returned.
+func resolveURL(path string, query url.Values) (string, error) {
+	u := path
+	if strings.HasPrefix(path, "https://") || strings.HasPrefix(path, "http://") {
+		parsed, err := url.Parse(path)
+		if err != nil {
+			return "", err
+		}
+		root, _ := url.Parse(fetchAPI)
+		if parsed.Scheme != root.Scheme || !strings.EqualFold(parsed.Host, root.Host) ||
+			(parsed.Path != root.Path && !strings.HasPrefix(parsed.Path, root.Path+"/")) {
+			return "", fmt.Errorf("%w: %s", ErrForeignURL, path)
+		}
+	} else {
+		u = fetchAPI + "/" + strings.TrimPrefix(path, "/")
+	}
+	if len(query) == 0 {
+		return u, nil
+	}
+	if strings.Contains(u, "?") {
+		return u + "&" + query.Encode(), nil
+	}
+	return u + "?" + query.Encode(), nil
+}
+
+// Raw does a GET request to the given path, which is either relative to the API root or an absolute URL pointing
+// to the API, and returns the response. The request is authenticated and rate limited like all the other requests,
+// and API errors are decoded and returned as errors. Use it for endpoints this package doesn't wrap yet. The caller
+// shall close the response body.
+func (a *API) Raw(ctx context.Context, path string) (*http.Response, error) {
+	u, err := resolveURL(path, nil)
+	if err != nil {
+		return nil, err
+	}
+	return a.request(ctx, http.MethodGet, u, nil)
+}
+
+// Do does a request with the given method to the given path, which is either relative to the API root or an
+// absolute URL pointing to the API, and decodes the response into out. The response may be either JSON or XML,
+// the format being detected from the body, so out shall have both xml and json struct tags. The json tags may use
+// paths like the ones used by this package's types (for example "FlightStatusResource.Flights.Flight"). If out is
+// nil, the response is discarded. GET requests go through the response cache.
+// Only the methods of requests without a body are supported (GET, HEAD, DELETE and OPTIONS), as the Lufthansa API
+// is read-only; other methods result in ErrUnsupportedMethod.
+func (a *API) Do(ctx context.Context, method, path string, query url.Values, out interface{}) error {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodDelete, http.MethodOptions:
+	default:
+		return fmt.Errorf("%w: %s", ErrUnsupportedMethod, method)
+	}
+	u, err := resolveURL(path, query)
+	if err != nil {
+		return err
+	}
+	var body io.ReadCloser
+	if method == http.MethodGet {
+		fetched, err := a.fetch(ctx, u)
+		if err != nil {
+			return err
+		}
+		body = fetched
+	} else {
+		res, err := a.request(ctx, method, u, nil)
+		if err != nil {
+			return err
+		}
+		body = res.Body
+	}
+	if out == nil {
+		_, err := util.ReadAll(body)
+		return err
+	}
+	return util.Decode(body, out)
 }
 
 // NewAPI constructs the API object, having as parametres the client's ID and client's secret.
-func NewAPI(ctx context.Context, id, secret string, reqPerSecond, reqPerHour int) (*API, error) {
+// By default, responses are cached in memory according to the durations returned by DefaultCacheTTLs; use the options to change that.
+// A circuit breaker makes requests fail fast while the API is failing; see WithCircuitBreaker.
+// Requests that the rate limits don't allow to be sent before the context's deadline fail immediately, with an
+// error matching ratelimithttp.ErrQuotaExhausted. The 15 seconds request timeout starts once a request is allowed.
+func NewAPI(ctx context.Context, id, secret string, reqPerSecond, reqPerHour int, opts ...Option) (*API, error) {
 	ret := &API{
-		client: ratelimithttp.NewClient(
-			&http.Client{
-				Timeout: time.Second * 15,
-			},
-			rate.NewLimiter(rate.Every(time.Second), reqPerSecond),
-			rate.NewLimiter(rate.Every(time.Hour), reqPerHour),
-		),
 		clientID:     id,
 		clientSecret: secret,
+		cache:        newResponseCache(),
+		breaker:      circuitbreaker.New(circuitbreaker.Config{}),
+	}
+	for _, o := range opts {
+		o(ret)
 	}
+	ret.client = ret.newClient(reqPerSecond, reqPerHour)
 	if err := ret.setToken(ctx); err != nil {
 		return nil, err
 	}
@@ -1,6 +1,7 @@
 package lufthansa
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -31,7 +32,9 @@ type (
 	}
 	// unknownError is a placeholder for error types that might not be documented. The struct holds the raw API response.
 	unknownError struct {
-		response string
+		status     string
+		statusCode int
+		response   string
 	}
 )
 
@@ -72,7 +75,10 @@ func (ae *APIError) decode(r io.ReadCloser) error {
 }
 
 func (ue *unknownError) Error() string {
-	return ue.response
+	if ue.status == "" {
+		return ue.response
+	}
+	return ue.status + ": " + ue.response
 }
 
 func (ue *unknownError) decode(r io.ReadCloser) error {
@@ -84,20 +90,28 @@ func (ue *unknownError) decode(r io.ReadCloser) error {
 	return nil
 }
 
+// errNotModified is returned by decodeErrors when the API responds to a conditional request with 304 Not Modified.
+// It isn't an actual error: it signals that the data the caller already has is up to date.
+var errNotModified = errors.New("lufthansa: not modified")
+
 // decodeErrors decodes the API response, according to the HTTP status code. If the API responded with an error, the
 // response body will be closed, no further reading being possible.
 func decodeErrors(res *http.Response) error {
 	var apiError error
 
 	switch res.StatusCode {
-	case http.StatusOK:
-		return nil
+	case http.StatusNotModified:
+		_ = res.Body.Close()
+		return errNotModified
 	case http.StatusUnauthorized, http.StatusForbidden:
 		apiError = &GatewayError{}
 	case http.StatusBadRequest, http.StatusNotFound, http.StatusMethodNotAllowed:
 		apiError = &APIError{}
-	case http.StatusInternalServerError:
-		apiError = &unknownError{}
+	default:
+		if res.StatusCode >= 200 && res.StatusCode < 300 {
+			return nil
+		}
+		apiError = &unknownError{status: res.Status, statusCode: res.StatusCode}
 	}
 	if err := apiError.(apiResponse).decode(res.Body); err != nil {
 		return err
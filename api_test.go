@@ -0,0 +1,76 @@
+package lufthansa_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+
+	lufthansa "github.com/tmaxmax/lufthansaapi"
+	"github.com/tmaxmax/lufthansaapi/pkg/ratelimithttp"
+)
+
+func TestAPI_Do(t *testing.T) {
+	var out struct {
+		Code string `xml:"Countries>Country>CountryCode" json:"CountryResource.Countries.Country.CountryCode"`
+	}
+	if err := api.Do(ctx, http.MethodGet, "/mds-references/countries/DE", url.Values{"lang": {"en"}}, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Code != "DE" {
+		t.Fatalf("expected country code DE, got %q", out.Code)
+	}
+}
+
+func TestAPI_Raw(t *testing.T) {
+	res, err := api.Raw(ctx, "mds-references/countries/RO")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	t.Log(res.Status, res.Header.Get("Content-Type"))
+}
+
+func TestAPI_Raw_ForeignURL(t *testing.T) {
+	for _, u := range []string{
+		"https://example.com/v1/mds-references/countries/RO",
+		"http://api.lufthansa.com/v1/mds-references/countries/RO",
+		"https://api.lufthansa.com/v2/mds-references/countries/RO",
+	} {
+		if _, err := api.Raw(ctx, u); !errors.Is(err, lufthansa.ErrForeignURL) {
+			t.Errorf("Raw(%q): expected %v, got %v", u, lufthansa.ErrForeignURL, err)
+		}
+	}
+	res, err := api.Raw(ctx, "https://api.lufthansa.com/v1/mds-references/countries/RO")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+}
+
+func TestAPI_Do_UnsupportedMethod(t *testing.T) {
+	if err := api.Do(ctx, http.MethodPost, "/mds-references/countries", nil, nil); !errors.Is(err, lufthansa.ErrUnsupportedMethod) {
+		t.Fatalf("expected %v, got %v", lufthansa.ErrUnsupportedMethod, err)
+	}
+}
+
+func TestAPI_QuotaFailFast(t *testing.T) {
+	// the only request of the hour is used to get the access token
+	a, err := lufthansa.NewAPI(ctx, os.Getenv("LOA_ID"), os.Getenv("LOA_SECRET"), 1, 1, lufthansa.WithCache(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	short, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	start := time.Now()
+	if _, err = a.FetchCountry(short, "DE", nil); !errors.Is(err, ratelimithttp.ErrQuotaExhausted) {
+		t.Fatalf("expected %v, got %v", ratelimithttp.ErrQuotaExhausted, err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("expected to fail fast, waited %s", elapsed)
+	}
+}
@@ -0,0 +1,228 @@
+package lufthansa
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/tmaxmax/lufthansaapi/internal/util"
+	"github.com/tmaxmax/lufthansaapi/pkg/lru"
+)
+
+// Cache stores the bodies of successful API responses. Implementations must be safe for concurrent use and may keep
+// the data anywhere (in memory, on disk, in a shared store). The keys are normalized request URLs, prefixed with the
+// requested language; they may be long, so implementations with key length limits should hash them.
+type Cache interface {
+	// Get returns the data stored for the key, if it exists and hasn't expired.
+	Get(key string) ([]byte, bool)
+	// Set stores the data for the key, for the given duration.
+	Set(key string, data []byte, ttl time.Duration)
+}
+
+// defaultCacheSize is the number of responses kept by the default in-memory cache.
+const defaultCacheSize = 1024
+
+// defaultCacheTTLs holds the durations responses are cached for, by endpoint path. It must not be modified.
+var defaultCacheTTLs = map[string]time.Duration{
+	"/mds-references":          24 * time.Hour,
+	"/references":              24 * time.Hour,
+	"/offers/lounges":          time.Hour,
+	"/offers/seatmaps":         5 * time.Minute,
+	"/operations/schedules":    time.Hour,
+	"/operations/flightstatus": time.Minute,
+	"/cargo/getRoute":          time.Hour,
+	"/cargo/shipmentTracking":  time.Minute,
+}
+
+// DefaultCacheTTLs returns the durations responses are cached for, by endpoint path (relative to the API root).
+// The entry with the longest matching prefix is used; responses of endpoints not listed here aren't cached.
+// The returned map is a copy, so modifying it has no effect; use WithCacheTTL to change the durations for an API.
+func DefaultCacheTTLs() map[string]time.Duration {
+	ret := make(map[string]time.Duration, len(defaultCacheTTLs))
+	for prefix, ttl := range defaultCacheTTLs {
+		ret[prefix] = ttl
+	}
+	return ret
+}
+
+// CacheStats holds the number of cacheable requests that were served from the cache and the number of those that
+// had to be fetched.
+type CacheStats struct {
+	Hits   uint64
+	Misses uint64
+}
+
+type cacheTTL struct {
+	prefix string
+	ttl    time.Duration
+}
+
+// responseCache holds the cache configuration of an API. It is allocated separately so that
+// the atomically accessed counters are 64-bit aligned.
+type responseCache struct {
+	hits   uint64
+	misses uint64
+	cache  Cache
+	// ttls is sorted by descending prefix length, so the first match is the longest one.
+	ttls []cacheTTL
+}
+
+func newResponseCache() *responseCache {
+	rc := &responseCache{cache: lru.New(defaultCacheSize)}
+	for prefix, ttl := range defaultCacheTTLs {
+		rc.setTTL(prefix, ttl)
+	}
+	return rc
+}
+
+func (rc *responseCache) setTTL(prefix string, ttl time.Duration) {
+	prefix = "/" + strings.Trim(prefix, "/")
+	for i := range rc.ttls {
+		if rc.ttls[i].prefix == prefix {
+			rc.ttls[i].ttl = ttl
+			return
+		}
+	}
+	rc.ttls = append(rc.ttls, cacheTTL{prefix, ttl})
+	sort.SliceStable(rc.ttls, func(i, j int) bool {
+		return len(rc.ttls[i].prefix) > len(rc.ttls[j].prefix)
+	})
+}
+
+// ttl returns the duration the response of the given path may be cached for.
+func (rc *responseCache) ttl(path string) time.Duration {
+	for _, t := range rc.ttls {
+		if path == t.prefix || strings.HasPrefix(path, t.prefix+"/") {
+			return t.ttl
+		}
+	}
+	return 0
+}
+
+// cacheKey normalizes the request URL: the scheme and host are lowercased, trailing slashes are removed from the
+// path and the query parameters are sorted. The path relative to the API root is also returned, for TTL lookups.
+func cacheKey(rawURL string) (key, path string, err error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", "", err
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	u.Path = strings.TrimRight(u.Path, "/")
+	q := u.Query()
+	u.RawQuery = q.Encode()
+	u.Fragment = ""
+
+	path = u.Path
+	if root, err := url.Parse(fetchAPI); err == nil && strings.HasPrefix(path, root.Path) {
+		path = strings.TrimPrefix(path, root.Path)
+	}
+	return "lang=" + q.Get("lang") + " " + u.String(), path, nil
+}
+
+type cacheBypassKey struct{}
+
+// WithoutCache returns a context that makes the requests done with it skip the API's response cache: the responses
+// are always fetched, and they aren't stored.
+func WithoutCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, cacheBypassKey{}, true)
+}
+
+func cacheBypassed(ctx context.Context) bool {
+	bypass, _ := ctx.Value(cacheBypassKey{}).(bool)
+	return bypass
+}
+
+// entry returns the cache key and TTL of the URL's response. It returns false if the response must not be cached.
+func (rc *responseCache) entry(ctx context.Context, rawURL string) (key string, ttl time.Duration, ok bool) {
+	if rc == nil || rc.cache == nil || cacheBypassed(ctx) {
+		return "", 0, false
+	}
+	key, path, err := cacheKey(rawURL)
+	if err != nil {
+		return "", 0, false
+	}
+	ttl = rc.ttl(path)
+	return key, ttl, ttl > 0
+}
+
+// store caches the response data for the URL, if the endpoint is cacheable.
+func (rc *responseCache) store(ctx context.Context, rawURL string, data []byte) {
+	if key, ttl, ok := rc.entry(ctx, rawURL); ok {
+		rc.cache.Set(key, data, ttl)
+	}
+}
+
+// fetchCached returns the response for the URL from the cache, if the endpoint is cacheable, or fetches it using
+// fetch and stores it.
+func (rc *responseCache) fetchCached(ctx context.Context, rawURL string, fetch func() (io.ReadCloser, error)) (io.ReadCloser, error) {
+	key, ttl, ok := rc.entry(ctx, rawURL)
+	if !ok {
+		return fetch()
+	}
+	if data, ok := rc.cache.Get(key); ok {
+		atomic.AddUint64(&rc.hits, 1)
+		return ioutil.NopCloser(bytes.NewReader(data)), nil
+	}
+	atomic.AddUint64(&rc.misses, 1)
+
+	body, err := fetch()
+	if err != nil {
+		return nil, err
+	}
+	data, err := util.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+	rc.cache.Set(key, data, ttl)
+	return ioutil.NopCloser(bytes.NewReader(data)), nil
+}
+
+// CacheStats returns the response cache's hit and miss counts.
+func (a *API) CacheStats() CacheStats {
+	if a.cache == nil {
+		return CacheStats{}
+	}
+	return CacheStats{
+		Hits:   atomic.LoadUint64(&a.cache.hits),
+		Misses: atomic.LoadUint64(&a.cache.misses),
+	}
+}
+
+// WithCache makes the API use the given cache for responses, instead of the default in-memory LRU cache
+// of 1024 responses. Passing nil, or a nil pointer of a type implementing Cache, disables caching.
+func WithCache(c Cache) Option {
+	if isNil(c) {
+		c = nil
+	}
+	return func(a *API) {
+		a.cache.cache = c
+	}
+}
+
+// isNil reports whether the interface is nil or holds a nil value.
+func isNil(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	switch rv := reflect.ValueOf(v); rv.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan, reflect.Interface:
+		return rv.IsNil()
+	}
+	return false
+}
+
+// WithCacheTTL sets the duration the responses of the endpoints whose path starts with the given prefix
+// (for example "/mds-references/countries") are cached for. A zero duration disables caching for them.
+func WithCacheTTL(pathPrefix string, ttl time.Duration) Option {
+	return func(a *API) {
+		a.cache.setTTL(pathPrefix, ttl)
+	}
+}
@@ -0,0 +1,38 @@
+package lufthansa_test
+
+import (
+	"testing"
+
+	lufthansa "github.com/tmaxmax/lufthansaapi"
+)
+
+func TestAPI_CacheStats(t *testing.T) {
+	before := api.CacheStats()
+	for i := 0; i < 2; i++ {
+		if _, err := api.FetchCountry(ctx, "FR", nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	after := api.CacheStats()
+	if hits := after.Hits - before.Hits; hits < 1 {
+		t.Fatalf("expected the second request to hit the cache, got %d hits", hits)
+	}
+
+	if _, err := api.FetchCountry(lufthansa.WithoutCache(ctx), "FR", nil); err != nil {
+		t.Fatal(err)
+	}
+	if bypassed := api.CacheStats(); bypassed != after {
+		t.Fatalf("expected bypassed request not to touch the cache, stats changed from %+v to %+v", after, bypassed)
+	}
+}
+
+func TestDefaultCacheTTLs(t *testing.T) {
+	ttls := lufthansa.DefaultCacheTTLs()
+	if ttls["/mds-references"] == 0 {
+		t.Fatal("expected the reference endpoints to be cached by default")
+	}
+	ttls["/mds-references"] = 0
+	if lufthansa.DefaultCacheTTLs()["/mds-references"] == 0 {
+		t.Fatal("expected DefaultCacheTTLs to return a copy")
+	}
+}
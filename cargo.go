@@ -0,0 +1,267 @@
+package lufthansa
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/tmaxmax/lufthansaapi/internal/util"
+)
+
+const cargoAPI = fetchAPI + "/cargo"
+
+// ErrInvalidAWB is returned when an air waybill number is malformed or its check digit is wrong.
+var ErrInvalidAWB = errors.New("lufthansa: cargo: invalid air waybill number")
+
+// AWB is an air waybill number: a 3 digit airline prefix (020 for Lufthansa Cargo) and an 8 digit serial number,
+// whose last digit is the check digit.
+type AWB struct {
+	Prefix string
+	Serial string
+}
+
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// ParseAWB parses an air waybill number written either as "020-12345675", "020 12345675" or "02012345675".
+// The check digit is validated.
+func ParseAWB(s string) (AWB, error) {
+	digits := strings.NewReplacer("-", "", " ", "").Replace(strings.TrimSpace(s))
+	if len(digits) != 11 || !isDigits(digits) {
+		return AWB{}, fmt.Errorf("%w: %q", ErrInvalidAWB, s)
+	}
+	awb := AWB{Prefix: digits[:3], Serial: digits[3:]}
+	return awb, awb.Validate()
+}
+
+// Validate checks that the prefix and serial number have the right length and that the check digit,
+// the remainder of the first 7 serial digits divided by 7, is correct.
+func (a AWB) Validate() error {
+	if len(a.Prefix) != 3 || len(a.Serial) != 8 || !isDigits(a.Prefix) || !isDigits(a.Serial) {
+		return fmt.Errorf("%w: %q", ErrInvalidAWB, a.String())
+	}
+	n := 0
+	for i := 0; i < 7; i++ {
+		n = n*10 + int(a.Serial[i]-'0')
+	}
+	if check := int(a.Serial[7] - '0'); n%7 != check {
+		return fmt.Errorf("%w: %q: check digit is %d, expected %d", ErrInvalidAWB, a.String(), check, n%7)
+	}
+	return nil
+}
+
+func (a AWB) String() string {
+	return a.Prefix + "-" + a.Serial
+}
+
+type (
+	cargoWeightUnmarshal struct {
+		Value float64 `xml:",chardata" json:"$"`
+		Unit  string  `xml:"Unit,attr" json:"@Unit"`
+	}
+	shipmentEventUnmarshal struct {
+		StatusCode   string               `xml:"StatusCode" json:"StatusCode"`
+		Description  string               `xml:"Description" json:"Description"`
+		Station      string               `xml:"Station" json:"Station"`
+		DateTime     string               `xml:"DateTime" json:"DateTime"`
+		FlightNumber string               `xml:"FlightNumber" json:"FlightNumber"`
+		Pieces       int                  `xml:"Pieces" json:"Pieces"`
+		Weight       cargoWeightUnmarshal `xml:"Weight" json:"Weight"`
+	}
+	shipmentUnmarshal struct {
+		Prefix      string                   `xml:"Shipment>AWBPrefix" json:"ShipmentTrackingResource.Shipment.AWBPrefix"`
+		Serial      string                   `xml:"Shipment>AWBNumber" json:"ShipmentTrackingResource.Shipment.AWBNumber"`
+		Origin      string                   `xml:"Shipment>Origin" json:"ShipmentTrackingResource.Shipment.Origin"`
+		Destination string                   `xml:"Shipment>Destination" json:"ShipmentTrackingResource.Shipment.Destination"`
+		Pieces      int                      `xml:"Shipment>Pieces" json:"ShipmentTrackingResource.Shipment.Pieces"`
+		Weight      cargoWeightUnmarshal     `xml:"Shipment>Weight" json:"ShipmentTrackingResource.Shipment.Weight"`
+		Events      []shipmentEventUnmarshal `xml:"Shipment>Events>Event" json:"ShipmentTrackingResource.Shipment.Events.Event"`
+	}
+	cargoLegUnmarshal struct {
+		FlightNumber string `xml:"FlightNumber" json:"FlightNumber"`
+		Origin       string `xml:"Departure>AirportCode" json:"Departure.AirportCode"`
+		Departure    string `xml:"Departure>ScheduledTimeLocal>DateTime" json:"Departure.ScheduledTimeLocal.DateTime"`
+		Destination  string `xml:"Arrival>AirportCode" json:"Arrival.AirportCode"`
+		Arrival      string `xml:"Arrival>ScheduledTimeLocal>DateTime" json:"Arrival.ScheduledTimeLocal.DateTime"`
+		AircraftType string `xml:"AircraftType" json:"AircraftType"`
+	}
+	cargoRouteUnmarshal struct {
+		Legs []cargoLegUnmarshal `xml:"Legs>Leg" json:"Legs.Leg"`
+	}
+	cargoRoutesUnmarshal struct {
+		Routes []cargoRouteUnmarshal `xml:"Routes>Route" json:"RouteResource.Routes.Route"`
+	}
+
+	// CargoWeight is a weight, as sent by the Cargo API. Unit is usually KG.
+	CargoWeight struct {
+		Value float64
+		Unit  string
+	}
+	// ShipmentEvent is a status update of a shipment, for example RCS (received from shipper) or DLV (delivered).
+	// Time is the local time of the station where the event occurred.
+	ShipmentEvent struct {
+		StatusCode   string
+		Description  string
+		Station      string
+		Time         time.Time
+		FlightNumber string
+		Pieces       int
+		Weight       CargoWeight
+	}
+	// Shipment represents the decoded API response of the shipment tracking endpoint. Events are sorted
+	// chronologically, forming the shipment's timeline.
+	// Lufthansa API documentation: https://developer.lufthansa.com/docs/read/api_details/cargo
+	Shipment struct {
+		AWB         AWB
+		Origin      string
+		Destination string
+		Pieces      int
+		Weight      CargoWeight
+		Events      []ShipmentEvent
+	}
+	// CargoLeg is a single flight of a cargo route. The times are local to the respective airports.
+	CargoLeg struct {
+		FlightNumber string
+		Origin       string
+		Destination  string
+		Departure    time.Time
+		Arrival      time.Time
+		AircraftType string
+	}
+	// CargoRoute is a connection between the requested origin and destination, made of one or more flights.
+	CargoRoute struct {
+		Legs []CargoLeg
+	}
+	// CargoRoutes represents the decoded API response of the route lookup endpoint.
+	// Lufthansa API documentation: https://developer.lufthansa.com/docs/read/api_details/cargo
+	CargoRoutes struct {
+		Routes []CargoRoute
+	}
+)
+
+func (e *ShipmentEvent) make(eu *shipmentEventUnmarshal) (err error) {
+	e.StatusCode = eu.StatusCode
+	e.Description = eu.Description
+	e.Station = eu.Station
+	e.FlightNumber = eu.FlightNumber
+	e.Pieces = eu.Pieces
+	e.Weight = CargoWeight(eu.Weight)
+	e.Time, err = parseLocalDateTime(eu.DateTime)
+	return err
+}
+
+func (s *Shipment) decode(r io.ReadCloser) error {
+	su := &shipmentUnmarshal{}
+	if err := util.Decode(r, su); err != nil {
+		return err
+	}
+	s.AWB = AWB{Prefix: su.Prefix, Serial: su.Serial}
+	s.Origin = su.Origin
+	s.Destination = su.Destination
+	s.Pieces = su.Pieces
+	s.Weight = CargoWeight(su.Weight)
+	s.Events = make([]ShipmentEvent, len(su.Events))
+	for i := range su.Events {
+		if err := s.Events[i].make(&su.Events[i]); err != nil {
+			return err
+		}
+	}
+	sort.SliceStable(s.Events, func(i, j int) bool {
+		return s.Events[i].Time.Before(s.Events[j].Time)
+	})
+	return nil
+}
+
+// Latest returns the most recent event of the shipment, or nil if there are no events.
+func (s *Shipment) Latest() *ShipmentEvent {
+	if len(s.Events) == 0 {
+		return nil
+	}
+	return &s.Events[len(s.Events)-1]
+}
+
+func (s *Shipment) String() string {
+	return util.Stringer.Stringify(s, "")
+}
+
+func (l *CargoLeg) make(lu *cargoLegUnmarshal) (err error) {
+	l.FlightNumber = lu.FlightNumber
+	l.Origin = lu.Origin
+	l.Destination = lu.Destination
+	l.AircraftType = lu.AircraftType
+	if l.Departure, err = parseLocalDateTime(lu.Departure); err != nil {
+		return err
+	}
+	l.Arrival, err = parseLocalDateTime(lu.Arrival)
+	return err
+}
+
+func (cr *CargoRoutes) decode(r io.ReadCloser) error {
+	cu := &cargoRoutesUnmarshal{}
+	if err := util.Decode(r, cu); err != nil {
+		return err
+	}
+	cr.Routes = make([]CargoRoute, len(cu.Routes))
+	for i := range cu.Routes {
+		cr.Routes[i].Legs = make([]CargoLeg, len(cu.Routes[i].Legs))
+		for j := range cu.Routes[i].Legs {
+			if err := cr.Routes[i].Legs[j].make(&cu.Routes[i].Legs[j]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (cr *CargoRoutes) String() string {
+	return util.Stringer.Stringify(cr, "")
+}
+
+// CargoAPI groups the Lufthansa Cargo endpoints. Obtain it with API.Cargo; it shares the authentication
+// and rate limits of the API it was obtained from.
+type CargoAPI struct {
+	api *API
+}
+
+// Cargo returns the Cargo sub-API.
+func (a *API) Cargo() *CargoAPI {
+	return &CargoAPI{api: a}
+}
+
+// TrackShipment requests the status of the shipment with the given air waybill number.
+// The number is validated before doing the request, so a malformed one results in ErrInvalidAWB.
+func (c *CargoAPI) TrackShipment(ctx context.Context, awb AWB) (*Shipment, error) {
+	if err := awb.Validate(); err != nil {
+		return nil, err
+	}
+	fetched, err := c.api.fetch(ctx, fmt.Sprintf("%s/shipmentTracking/%s-%s", cargoAPI, awb.Prefix, awb.Serial))
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Shipment{}
+	return s, s.decode(fetched)
+}
+
+// FetchRoute requests the flight connections for the given product code between origin and destination,
+// starting from the given date. Only the date part of the date parameter is used.
+func (c *CargoAPI) FetchRoute(ctx context.Context, origin, destination string, date time.Time, productCode string) (*CargoRoutes, error) {
+	url := fmt.Sprintf("%s/getRoute/%s-%s/%s/%s", cargoAPI, origin, destination, date.Format(dateLayout), productCode)
+	fetched, err := c.api.fetch(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+
+	cr := &CargoRoutes{}
+	return cr, cr.decode(fetched)
+}
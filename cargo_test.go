@@ -0,0 +1,52 @@
+package lufthansa_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	lufthansa "github.com/tmaxmax/lufthansaapi"
+)
+
+func TestParseAWB(t *testing.T) {
+	tests := []struct {
+		input string
+		valid bool
+	}{
+		{"020-12345675", true},
+		{"020 12345675", true},
+		{"02012345675", true},
+		{"020-12345674", false},
+		{"020-1234567", false},
+		{"02A-12345675", false},
+	}
+	for _, test := range tests {
+		awb, err := lufthansa.ParseAWB(test.input)
+		if test.valid && err != nil {
+			t.Fatalf("%s: unexpected error: %v", test.input, err)
+		}
+		if !test.valid && !errors.Is(err, lufthansa.ErrInvalidAWB) {
+			t.Fatalf("%s: expected ErrInvalidAWB, got %v", test.input, err)
+		}
+		if test.valid && awb.String() != "020-12345675" {
+			t.Fatalf("%s: unexpected AWB %s", test.input, awb)
+		}
+	}
+}
+
+func TestCargoAPI_TrackShipment(t *testing.T) {
+	awb, _ := lufthansa.ParseAWB("020-12345675")
+	s, err := api.Cargo().TrackShipment(ctx, awb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("%s", s)
+}
+
+func TestCargoAPI_FetchRoute(t *testing.T) {
+	r, err := api.Cargo().FetchRoute(ctx, "FRA", "JFK", time.Now().AddDate(0, 0, 7), "YNZ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("%s", r)
+}
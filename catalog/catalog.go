@@ -0,0 +1,246 @@
+// Package catalog keeps the Lufthansa reference data (countries, cities and airports) in memory, indexed for
+// lookups and queries that don't need any API calls. Use Sync to download the complete dataset.
+//
+// Aircraft and airlines aren't part of the catalog yet, as the lufthansa package doesn't decode them.
+package catalog
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	lufthansa "github.com/tmaxmax/lufthansaapi"
+)
+
+// pageLimit is the maximum number of records the reference endpoints return per request.
+const pageLimit = 100
+
+// Catalog is an in-memory, indexed copy of the reference data. It is read-only after creation, so it is safe
+// for concurrent use. The values returned by its methods point into the catalog and must not be modified.
+type Catalog struct {
+	// FetchedAt is the time the data was downloaded from the API.
+	FetchedAt time.Time
+
+	countries  []lufthansa.Country
+	cities     []lufthansa.City
+	airports   []lufthansa.Airport
+	lhOperated map[string]bool
+
+	countryIndex      map[string]int
+	cityIndex         map[string]int
+	airportIndex      map[string]int
+	citiesByCountry   map[string][]int
+	airportsByCountry map[string][]int
+	airportsByCity    map[string][]int
+}
+
+// New creates a catalog from the given records. lhOperated contains the codes of the airports operated by
+// Lufthansa; codes of airports that aren't among the records are ignored. The slices are owned by the catalog after the call, and the records are sorted by their codes.
+func New(fetchedAt time.Time, countries []lufthansa.Country, cities []lufthansa.City, airports []lufthansa.Airport, lhOperated []string) *Catalog {
+	c := &Catalog{
+		FetchedAt:         fetchedAt,
+		countries:         countries,
+		cities:            cities,
+		airports:          airports,
+		lhOperated:        make(map[string]bool, len(lhOperated)),
+		countryIndex:      make(map[string]int, len(countries)),
+		cityIndex:         make(map[string]int, len(cities)),
+		airportIndex:      make(map[string]int, len(airports)),
+		citiesByCountry:   make(map[string][]int),
+		airportsByCountry: make(map[string][]int),
+		airportsByCity:    make(map[string][]int),
+	}
+	sort.SliceStable(c.countries, func(i, j int) bool { return c.countries[i].CountryCode < c.countries[j].CountryCode })
+	sort.SliceStable(c.cities, func(i, j int) bool { return c.cities[i].CityCode < c.cities[j].CityCode })
+	sort.SliceStable(c.airports, func(i, j int) bool { return c.airports[i].AirportCode < c.airports[j].AirportCode })
+
+	for i := range c.countries {
+		c.countryIndex[c.countries[i].CountryCode] = i
+	}
+	for i := range c.cities {
+		city := &c.cities[i]
+		c.cityIndex[city.CityCode] = i
+		c.citiesByCountry[city.CountryCode] = append(c.citiesByCountry[city.CountryCode], i)
+	}
+	for i := range c.airports {
+		a := &c.airports[i]
+		c.airportIndex[a.AirportCode] = i
+		c.airportsByCountry[a.CountryCode] = append(c.airportsByCountry[a.CountryCode], i)
+		c.airportsByCity[a.CityCode] = append(c.airportsByCity[a.CityCode], i)
+	}
+	for _, code := range lhOperated {
+		if _, ok := c.airportIndex[code]; ok {
+			c.lhOperated[code] = true
+		}
+	}
+	return c
+}
+
+// Sync downloads all the countries, cities and airports, in all the available languages, and builds a catalog
+// from them. Pages of the maximum size are requested, so a full sync takes a few hundred requests.
+func Sync(ctx context.Context, api *lufthansa.API) (*Catalog, error) {
+	p := func() *lufthansa.RefParams {
+		return &lufthansa.RefParams{Limit: pageLimit}
+	}
+	fetchedAt := time.Now()
+
+	var countries []lufthansa.Country
+	cs := api.FetchCountries(p())
+	for cs.Next(ctx) {
+		countries = append(countries, cs.Countries...)
+	}
+	if err := cs.Error(); err != nil {
+		return nil, err
+	}
+
+	var cities []lufthansa.City
+	cis := api.FetchCities(p())
+	for cis.Next(ctx) {
+		cities = append(cities, cis.Cities...)
+	}
+	if err := cis.Error(); err != nil {
+		return nil, err
+	}
+
+	var airports []lufthansa.Airport
+	as := api.FetchAirports(p(), false)
+	for as.Next(ctx) {
+		airports = append(airports, as.Airports...)
+	}
+	if err := as.Error(); err != nil {
+		return nil, err
+	}
+
+	var lhOperated []string
+	lhs := api.FetchAirports(p(), true)
+	for lhs.Next(ctx) {
+		for i := range lhs.Airports {
+			lhOperated = append(lhOperated, lhs.Airports[i].AirportCode)
+		}
+	}
+	if err := lhs.Error(); err != nil {
+		return nil, err
+	}
+
+	return New(fetchedAt, countries, cities, airports, lhOperated), nil
+}
+
+// Countries returns all the countries, sorted by their codes.
+func (c *Catalog) Countries() []lufthansa.Country {
+	return c.countries
+}
+
+// Cities returns all the cities, sorted by their codes.
+func (c *Catalog) Cities() []lufthansa.City {
+	return c.cities
+}
+
+// Airports returns all the airports, sorted by their codes.
+func (c *Catalog) Airports() []lufthansa.Airport {
+	return c.airports
+}
+
+// Country returns the country with the given code, if it exists.
+func (c *Catalog) Country(code string) (*lufthansa.Country, bool) {
+	i, ok := c.countryIndex[code]
+	if !ok {
+		return nil, false
+	}
+	return &c.countries[i], true
+}
+
+// City returns the city with the given code, if it exists.
+func (c *Catalog) City(code string) (*lufthansa.City, bool) {
+	i, ok := c.cityIndex[code]
+	if !ok {
+		return nil, false
+	}
+	return &c.cities[i], true
+}
+
+// Airport returns the airport with the given code, if it exists.
+func (c *Catalog) Airport(code string) (*lufthansa.Airport, bool) {
+	i, ok := c.airportIndex[code]
+	if !ok {
+		return nil, false
+	}
+	return &c.airports[i], true
+}
+
+// LHOperated reports whether the airport with the given code is operated by Lufthansa.
+func (c *Catalog) LHOperated(airportCode string) bool {
+	return c.lhOperated[airportCode]
+}
+
+// CitiesIn returns the cities of the given country.
+func (c *Catalog) CitiesIn(countryCode string) []*lufthansa.City {
+	idx := c.citiesByCountry[countryCode]
+	ret := make([]*lufthansa.City, len(idx))
+	for i, j := range idx {
+		ret[i] = &c.cities[j]
+	}
+	return ret
+}
+
+// Query holds the criteria used by Catalog.FindAirports. Zero valued fields don't restrict the results.
+type Query struct {
+	CountryCode   string
+	CityCode      string
+	LHOperated    bool
+	LocationTypes []lufthansa.LocationType
+}
+
+func (q *Query) matches(c *Catalog, a *lufthansa.Airport) bool {
+	if q.CountryCode != "" && a.CountryCode != q.CountryCode {
+		return false
+	}
+	if q.CityCode != "" && a.CityCode != q.CityCode {
+		return false
+	}
+	if q.LHOperated && !c.lhOperated[a.AirportCode] {
+		return false
+	}
+	if len(q.LocationTypes) == 0 {
+		return true
+	}
+	for _, lt := range q.LocationTypes {
+		if a.LocationType == lt {
+			return true
+		}
+	}
+	return false
+}
+
+// FindAirports returns the airports matching the query, sorted by their codes. For example, all the airports
+// in Germany operated by Lufthansa are found with Query{CountryCode: "DE", LHOperated: true}.
+func (c *Catalog) FindAirports(q Query) []*lufthansa.Airport {
+	var candidates []int
+	switch {
+	case q.CityCode != "":
+		candidates = c.airportsByCity[q.CityCode]
+	case q.CountryCode != "":
+		candidates = c.airportsByCountry[q.CountryCode]
+	default:
+		candidates = make([]int, len(c.airports))
+		for i := range candidates {
+			candidates[i] = i
+		}
+	}
+	var ret []*lufthansa.Airport
+	for _, i := range candidates {
+		if q.matches(c, &c.airports[i]) {
+			ret = append(ret, &c.airports[i])
+		}
+	}
+	return ret
+}
+
+// AirportsIn returns the airports of the given country.
+func (c *Catalog) AirportsIn(countryCode string) []*lufthansa.Airport {
+	return c.FindAirports(Query{CountryCode: countryCode})
+}
+
+// AirportsOf returns the airports serving the given city.
+func (c *Catalog) AirportsOf(cityCode string) []*lufthansa.Airport {
+	return c.FindAirports(Query{CityCode: cityCode})
+}
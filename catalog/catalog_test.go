@@ -0,0 +1,90 @@
+package catalog
+
+import (
+	"testing"
+	"time"
+
+	lufthansa "github.com/tmaxmax/lufthansaapi"
+	"golang.org/x/text/language"
+)
+
+func testCatalog() *Catalog {
+	return New(time.Date(2020, time.August, 26, 12, 0, 0, 0, time.UTC),
+		[]lufthansa.Country{
+			{CountryCode: "RO", Names: map[language.Tag]string{language.English: "Romania"}},
+			{CountryCode: "DE", Names: map[language.Tag]string{language.English: "Germany", language.German: "Deutschland"}},
+		},
+		[]lufthansa.City{
+			{CityCode: "FRA", CountryCode: "DE", Names: map[language.Tag]string{language.English: "Frankfurt", language.French: "Francfort"}, Airports: []string{"FRA", "ZRB"}},
+			{CityCode: "MUC", CountryCode: "DE", Names: map[language.Tag]string{language.English: "Munich", language.German: "München"}, Airports: []string{"MUC"}},
+			{CityCode: "BUH", CountryCode: "RO", Names: map[language.Tag]string{language.English: "Bucharest"}, Airports: []string{"OTP"}},
+		},
+		[]lufthansa.Airport{
+			{AirportCode: "ZRB", CityCode: "FRA", CountryCode: "DE", LocationType: lufthansa.LocationRailwayStation, Position: lufthansa.Coordinate{Latitude: 50.1069, Longitude: 8.6633}},
+			{AirportCode: "FRA", CityCode: "FRA", CountryCode: "DE", LocationType: lufthansa.LocationAirport, Position: lufthansa.Coordinate{Latitude: 50.0333, Longitude: 8.5706}, Names: map[language.Tag]string{language.English: "Frankfurt/Main International Airport"}},
+			{AirportCode: "MUC", CityCode: "MUC", CountryCode: "DE", LocationType: lufthansa.LocationAirport, Position: lufthansa.Coordinate{Latitude: 48.3538, Longitude: 11.7861}, Names: map[language.Tag]string{language.English: "Munich International Airport"}},
+			{AirportCode: "OTP", CityCode: "BUH", CountryCode: "RO", LocationType: lufthansa.LocationAirport, Position: lufthansa.Coordinate{Latitude: 44.5711, Longitude: 26.085}, Names: map[language.Tag]string{language.English: "Henri Coanda International Airport"}},
+		},
+		[]string{"FRA", "MUC", "ZRB"},
+	)
+}
+
+func codes(airports []*lufthansa.Airport) []string {
+	ret := make([]string, len(airports))
+	for i := range airports {
+		ret[i] = airports[i].AirportCode
+	}
+	return ret
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestCatalog_Lookups(t *testing.T) {
+	c := testCatalog()
+
+	if country, ok := c.Country("DE"); !ok || country.Names[language.German] != "Deutschland" {
+		t.Fatalf("unexpected country lookup result %v, %t", country, ok)
+	}
+	if city, ok := c.City("MUC"); !ok || city.CountryCode != "DE" {
+		t.Fatalf("unexpected city lookup result %v, %t", city, ok)
+	}
+	if _, ok := c.Airport("JFK"); ok {
+		t.Fatal("found nonexistent airport")
+	}
+	if got := len(c.CitiesIn("DE")); got != 2 {
+		t.Fatalf("expected 2 cities in DE, got %d", got)
+	}
+	if c.Airports()[0].AirportCode != "FRA" {
+		t.Fatal("expected airports to be sorted by code")
+	}
+}
+
+func TestCatalog_FindAirports(t *testing.T) {
+	c := testCatalog()
+
+	tests := []struct {
+		query    Query
+		expected []string
+	}{
+		{Query{}, []string{"FRA", "MUC", "OTP", "ZRB"}},
+		{Query{CountryCode: "DE", LHOperated: true}, []string{"FRA", "MUC", "ZRB"}},
+		{Query{CountryCode: "DE", LocationTypes: []lufthansa.LocationType{lufthansa.LocationAirport}}, []string{"FRA", "MUC"}},
+		{Query{CityCode: "FRA"}, []string{"FRA", "ZRB"}},
+		{Query{CountryCode: "RO", LHOperated: true}, nil},
+	}
+	for _, test := range tests {
+		if got := codes(c.FindAirports(test.query)); !equal(got, test.expected) {
+			t.Fatalf("%+v: expected %v, got %v", test.query, test.expected, got)
+		}
+	}
+}
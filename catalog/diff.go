@@ -0,0 +1,217 @@
+package catalog
+
+import (
+	"context"
+	"reflect"
+	"sort"
+
+	lufthansa "github.com/tmaxmax/lufthansaapi"
+	"golang.org/x/text/language"
+)
+
+// ChangeKind tells how a record changed between two catalogs.
+type ChangeKind uint8
+
+// The kinds of changes reported by Diff.
+const (
+	Added ChangeKind = iota
+	Removed
+	Modified
+)
+
+func (k ChangeKind) String() string {
+	switch k {
+	case Added:
+		return "added"
+	case Removed:
+		return "removed"
+	case Modified:
+		return "modified"
+	}
+	return "unknown"
+}
+
+// Resource is the type of record a Change refers to.
+type Resource uint8
+
+// The resources kept in a catalog.
+const (
+	ResourceCountry Resource = iota
+	ResourceCity
+	ResourceAirport
+)
+
+func (r Resource) String() string {
+	switch r {
+	case ResourceCountry:
+		return "country"
+	case ResourceCity:
+		return "city"
+	case ResourceAirport:
+		return "airport"
+	}
+	return "unknown"
+}
+
+// Change describes how a single record changed. The Old and New fields of the change's Resource hold the record
+// from the old and new catalog, and all the other record fields are nil; the Old field is nil for added records
+// and the New field is nil for removed ones.
+//
+// For modified records, Fields contains the names of the changed fields other than Names (LHOperated is reported
+// for airports that started or stopped being operated by Lufthansa), and Names contains the languages whose
+// name was added, removed or changed.
+type Change struct {
+	Kind     ChangeKind
+	Resource Resource
+	Code     string
+	Fields   []string
+	Names    []language.Tag
+
+	OldCountry, NewCountry *lufthansa.Country
+	OldCity, NewCity       *lufthansa.City
+	OldAirport, NewAirport *lufthansa.Airport
+}
+
+// setRecords stores the old and new records in the fields of their type. Either of them may be nil.
+func (c *Change) setRecords(old, new interface{}) {
+	switch o := old.(type) {
+	case *lufthansa.Country:
+		c.OldCountry = o
+	case *lufthansa.City:
+		c.OldCity = o
+	case *lufthansa.Airport:
+		c.OldAirport = o
+	}
+	switch n := new.(type) {
+	case *lufthansa.Country:
+		c.NewCountry = n
+	case *lufthansa.City:
+		c.NewCity = n
+	case *lufthansa.Airport:
+		c.NewAirport = n
+	}
+}
+
+// diffNames returns the languages whose names differ, sorted by their string representation.
+func diffNames(old, new map[language.Tag]string) []language.Tag {
+	var tags []language.Tag
+	for tag, name := range old {
+		if n, ok := new[tag]; !ok || n != name {
+			tags = append(tags, tag)
+		}
+	}
+	for tag := range new {
+		if _, ok := old[tag]; !ok {
+			tags = append(tags, tag)
+		}
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i].String() < tags[j].String() })
+	return tags
+}
+
+// diffFields returns the names of the exported fields of the structs that old and new point to which aren't equal.
+// The Names field is skipped, as it is compared by diffNames.
+func diffFields(old, new interface{}) []string {
+	ov, nv := reflect.ValueOf(old).Elem(), reflect.ValueOf(new).Elem()
+	t := ov.Type()
+
+	var fields []string
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" || f.Name == "Names" {
+			continue
+		}
+		if !reflect.DeepEqual(ov.Field(i).Interface(), nv.Field(i).Interface()) {
+			fields = append(fields, f.Name)
+		}
+	}
+	return fields
+}
+
+// diffRecords compares the records of a resource. Both catalogs keep their records sorted by code,
+// so the comparison is a merge of the two lists.
+func diffRecords(resource Resource, oldLen, newLen int, code func(old bool, i int) string, record func(old bool, i int) (interface{}, map[language.Tag]string), extra func(code string) []string) []Change {
+	var changes []Change
+	i, j := 0, 0
+	for i < oldLen || j < newLen {
+		switch {
+		case j == newLen || (i < oldLen && code(true, i) < code(false, j)):
+			r, _ := record(true, i)
+			ch := Change{Kind: Removed, Resource: resource, Code: code(true, i)}
+			ch.setRecords(r, nil)
+			changes = append(changes, ch)
+			i++
+		case i == oldLen || code(true, i) > code(false, j):
+			r, _ := record(false, j)
+			ch := Change{Kind: Added, Resource: resource, Code: code(false, j)}
+			ch.setRecords(nil, r)
+			changes = append(changes, ch)
+			j++
+		default:
+			o, oNames := record(true, i)
+			n, nNames := record(false, j)
+			c := code(true, i)
+			fields := append(diffFields(o, n), extra(c)...)
+			names := diffNames(oNames, nNames)
+			if len(fields) > 0 || len(names) > 0 {
+				ch := Change{Kind: Modified, Resource: resource, Code: c, Fields: fields, Names: names}
+				ch.setRecords(o, n)
+				changes = append(changes, ch)
+			}
+			i++
+			j++
+		}
+	}
+	return changes
+}
+
+// Diff compares two catalogs and returns the changes needed to go from old to new: first the countries, then the
+// cities and then the airports, each sorted by code.
+func Diff(old, new *Catalog) []Change {
+	pick := func(isOld bool) *Catalog {
+		if isOld {
+			return old
+		}
+		return new
+	}
+	none := func(string) []string { return nil }
+
+	changes := diffRecords(ResourceCountry, len(old.countries), len(new.countries), func(isOld bool, i int) string {
+		return pick(isOld).countries[i].CountryCode
+	}, func(isOld bool, i int) (interface{}, map[language.Tag]string) {
+		c := &pick(isOld).countries[i]
+		return c, c.Names
+	}, none)
+
+	changes = append(changes, diffRecords(ResourceCity, len(old.cities), len(new.cities), func(isOld bool, i int) string {
+		return pick(isOld).cities[i].CityCode
+	}, func(isOld bool, i int) (interface{}, map[language.Tag]string) {
+		c := &pick(isOld).cities[i]
+		return c, c.Names
+	}, none)...)
+
+	changes = append(changes, diffRecords(ResourceAirport, len(old.airports), len(new.airports), func(isOld bool, i int) string {
+		return pick(isOld).airports[i].AirportCode
+	}, func(isOld bool, i int) (interface{}, map[language.Tag]string) {
+		a := &pick(isOld).airports[i]
+		return a, a.Names
+	}, func(code string) []string {
+		if old.lhOperated[code] != new.lhOperated[code] {
+			return []string{"LHOperated"}
+		}
+		return nil
+	})...)
+
+	return changes
+}
+
+// Update downloads the complete dataset again using Sync and compares it to the given catalog, which is usually
+// loaded from a snapshot. It returns the new catalog and the changes, so that caches built on the old data can be
+// updated record by record.
+func Update(ctx context.Context, api *lufthansa.API, old *Catalog) (*Catalog, []Change, error) {
+	fresh, err := Sync(ctx, api)
+	if err != nil {
+		return nil, nil, err
+	}
+	return fresh, Diff(old, fresh), nil
+}
@@ -0,0 +1,59 @@
+package catalog
+
+import (
+	"reflect"
+	"testing"
+
+	lufthansa "github.com/tmaxmax/lufthansaapi"
+	"golang.org/x/text/language"
+)
+
+func TestDiff(t *testing.T) {
+	old := testCatalog()
+
+	countries := append([]lufthansa.Country(nil), old.Countries()...)
+	countries[0].Names = map[language.Tag]string{language.English: "Federal Republic of Germany"}
+	countries = append(countries, lufthansa.Country{CountryCode: "US"})
+	cities := append([]lufthansa.City(nil), old.Cities()[1:]...)
+	airports := append([]lufthansa.Airport(nil), old.Airports()...)
+	airports[1].TimeZoneID = "Europe/Berlin"
+
+	new := New(old.FetchedAt, countries, cities, airports, []string{"FRA", "MUC", "ZRB", "OTP"})
+
+	type change struct {
+		Kind     ChangeKind
+		Resource Resource
+		Code     string
+		Fields   []string
+		Names    []language.Tag
+	}
+	expected := []change{
+		{Modified, ResourceCountry, "DE", nil, []language.Tag{language.German, language.English}},
+		{Added, ResourceCountry, "US", nil, nil},
+		{Removed, ResourceCity, "BUH", nil, nil},
+		{Modified, ResourceAirport, "MUC", []string{"TimeZoneID"}, nil},
+		{Modified, ResourceAirport, "OTP", []string{"LHOperated"}, nil},
+	}
+
+	changes := Diff(old, new)
+	got := make([]change, len(changes))
+	for i, c := range changes {
+		got[i] = change{c.Kind, c.Resource, c.Code, c.Fields, c.Names}
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected:\n%+v\ngot:\n%+v", expected, got)
+	}
+
+	if c := changes[1]; c.OldCountry != nil || c.NewCountry == nil || c.NewCountry.CountryCode != "US" || c.NewCity != nil || c.NewAirport != nil {
+		t.Fatal("expected added change to hold only the new country")
+	}
+	if c := changes[2]; c.OldCity == nil || c.OldCity.CityCode != "BUH" || c.NewCity != nil {
+		t.Fatal("expected removed change to hold only the old city")
+	}
+	if c := changes[3]; c.OldAirport == nil || c.NewAirport == nil || c.NewAirport.TimeZoneID != "Europe/Berlin" {
+		t.Fatal("expected modified change to hold both airports")
+	}
+	if len(Diff(old, old)) != 0 {
+		t.Fatal("expected no changes between identical catalogs")
+	}
+}
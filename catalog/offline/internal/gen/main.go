@@ -0,0 +1,34 @@
+// Command gen downloads the reference data from the Lufthansa API and writes it as a catalog snapshot.
+// It is run by go generate in the offline package; the API credentials are read from the LOA_ID and
+// LOA_SECRET environment variables.
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+
+	lufthansa "github.com/tmaxmax/lufthansaapi"
+	"github.com/tmaxmax/lufthansaapi/catalog"
+)
+
+func main() {
+	out := flag.String("o", "reference.jsonl.gz", "output file")
+	flag.Parse()
+
+	ctx := context.Background()
+	api, err := lufthansa.NewAPI(ctx, os.Getenv("LOA_ID"), os.Getenv("LOA_SECRET"), 5, 1000)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	c, err := catalog.Sync(ctx, api)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if err = c.SaveFile(*out); err != nil {
+		log.Fatalln(err)
+	}
+	m := c.Manifest()
+	log.Printf("wrote %d countries, %d cities and %d airports to %s", m.Countries, m.Cities, m.Airports, *out)
+}
@@ -0,0 +1,121 @@
+// Package offline provides the reference data without network access or credentials, using a snapshot of the
+// catalog embedded in the binary. Importing it adds the snapshot to the binary size, which is why it is a separate
+// package. The snapshot is refreshed from the live API by running go generate in this package, with the LOA_ID and
+// LOA_SECRET environment variables set to the API credentials.
+//
+// The repository doesn't ship the dataset itself: until go generate has been run, the embedded snapshot is empty
+// and every function of the package returns ErrNoSnapshot.
+package offline
+
+//go:generate go run ./internal/gen -o reference.jsonl.gz
+
+import (
+	"bytes"
+	"context"
+	_ "embed" // for the snapshot
+	"errors"
+	"sync"
+
+	lufthansa "github.com/tmaxmax/lufthansaapi"
+	"github.com/tmaxmax/lufthansaapi/catalog"
+)
+
+//go:embed reference.jsonl.gz
+var snapshot []byte
+
+var (
+	// ErrNotFound is returned when the requested record isn't in the embedded dataset.
+	ErrNotFound = errors.New("offline: record not found")
+	// ErrNoSnapshot is returned when the embedded snapshot is empty, because go generate wasn't run in this
+	// package.
+	ErrNoSnapshot = errors.New("offline: no snapshot embedded, run go generate in the offline package")
+)
+
+var (
+	loadOnce sync.Once
+	loaded   *catalog.Catalog
+	loadErr  error
+)
+
+// Catalog returns the catalog decoded from the embedded snapshot. The snapshot is decoded on the first call.
+func Catalog() (*catalog.Catalog, error) {
+	loadOnce.Do(func() {
+		if len(snapshot) == 0 {
+			loadErr = ErrNoSnapshot
+			return
+		}
+		loaded, loadErr = catalog.ReadSnapshot(bytes.NewReader(snapshot))
+	})
+	return loaded, loadErr
+}
+
+// Lookup is the reference lookup API shared by the online *lufthansa.Resolver and the offline Resolver,
+// so code using it can work with either.
+type Lookup interface {
+	Country(ctx context.Context, code string) (*lufthansa.Country, error)
+	City(ctx context.Context, code string) (*lufthansa.City, error)
+	Airport(ctx context.Context, code string) (*lufthansa.Airport, error)
+}
+
+var (
+	_ Lookup = (*lufthansa.Resolver)(nil)
+	_ Lookup = Resolver{}
+)
+
+// Resolver implements Lookup using the embedded dataset. The context is ignored, and, like the online resolver,
+// the returned values are copies. The zero value is ready to use.
+type Resolver struct{}
+
+// Country returns the country with the given code.
+func (Resolver) Country(_ context.Context, code string) (*lufthansa.Country, error) {
+	return Country(code)
+}
+
+// City returns the city with the given code.
+func (Resolver) City(_ context.Context, code string) (*lufthansa.City, error) {
+	return City(code)
+}
+
+// Airport returns the airport with the given code.
+func (Resolver) Airport(_ context.Context, code string) (*lufthansa.Airport, error) {
+	return Airport(code)
+}
+
+// Country returns the country with the given code.
+func Country(code string) (*lufthansa.Country, error) {
+	c, err := Catalog()
+	if err != nil {
+		return nil, err
+	}
+	country, ok := c.Country(code)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return country.Copy(), nil
+}
+
+// City returns the city with the given code.
+func City(code string) (*lufthansa.City, error) {
+	c, err := Catalog()
+	if err != nil {
+		return nil, err
+	}
+	city, ok := c.City(code)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return city.Copy(), nil
+}
+
+// Airport returns the airport with the given code.
+func Airport(code string) (*lufthansa.Airport, error) {
+	c, err := Catalog()
+	if err != nil {
+		return nil, err
+	}
+	airport, ok := c.Airport(code)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return airport.Copy(), nil
+}
@@ -0,0 +1,85 @@
+package offline
+
+import (
+	"context"
+	"testing"
+
+	lufthansa "github.com/tmaxmax/lufthansaapi"
+	"golang.org/x/text/language"
+)
+
+// generated reports whether go generate was run, checking that the package reports the missing snapshot otherwise.
+func generated(t *testing.T) bool {
+	t.Helper()
+	if _, err := Catalog(); err != ErrNoSnapshot {
+		if err != nil {
+			t.Fatal(err)
+		}
+		return true
+	}
+	var l Lookup = Resolver{}
+	ctx := context.Background()
+	if _, err := l.Country(ctx, "DE"); err != ErrNoSnapshot {
+		t.Fatalf("expected ErrNoSnapshot from Country, got %v", err)
+	}
+	if _, err := l.City(ctx, "FRA"); err != ErrNoSnapshot {
+		t.Fatalf("expected ErrNoSnapshot from City, got %v", err)
+	}
+	if _, err := l.Airport(ctx, "FRA"); err != ErrNoSnapshot {
+		t.Fatalf("expected ErrNoSnapshot from Airport, got %v", err)
+	}
+	return false
+}
+
+func TestLookup(t *testing.T) {
+	if !generated(t) {
+		return
+	}
+	var l Lookup = Resolver{}
+	ctx := context.Background()
+
+	airport, err := l.Airport(ctx, "FRA")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if airport.LocationType != lufthansa.LocationAirport || airport.CityCode != "FRA" {
+		t.Fatalf("unexpected airport %s", airport)
+	}
+	city, err := l.City(ctx, airport.CityCode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if city.Names[language.French] != "Francfort" {
+		t.Fatalf("unexpected city names %v", city.Names)
+	}
+	country, err := l.Country(ctx, city.CountryCode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if country.CountryCode != "DE" {
+		t.Fatalf("unexpected country %s", country)
+	}
+
+	if _, err = Airport("XXX"); err != ErrNotFound {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestAirport_ReturnsCopy(t *testing.T) {
+	if !generated(t) {
+		return
+	}
+	a, err := Airport("MUC")
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.Names[language.English] = "changed"
+
+	b, err := Airport("MUC")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Names[language.English] == "changed" {
+		t.Fatal("modifying a returned airport changed the embedded dataset")
+	}
+}
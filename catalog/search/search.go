@@ -0,0 +1,387 @@
+// Package search finds airports and cities by name in any of the languages sent by the reference API, or by their
+// IATA codes. Queries are matched case and diacritic insensitively, by prefix and with tolerance for typos.
+package search
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	lufthansa "github.com/tmaxmax/lufthansaapi"
+	"github.com/tmaxmax/lufthansaapi/catalog"
+	"golang.org/x/text/language"
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Kind is the type of record a Result refers to.
+type Kind uint8
+
+// The kinds of records in the index.
+const (
+	KindCity Kind = iota
+	KindAirport
+)
+
+func (k Kind) String() string {
+	switch k {
+	case KindCity:
+		return "city"
+	case KindAirport:
+		return "airport"
+	}
+	return fmt.Sprintf("Kind(%d)", k)
+}
+
+// Match is the way a result matched the query. Lower values are better matches.
+type Match uint8
+
+// The ways a query can match a record, from best to worst.
+const (
+	// MatchCode means the query is the record's IATA code.
+	MatchCode Match = iota
+	// MatchExact means the query is one of the record's names.
+	MatchExact
+	// MatchPrefix means the query is the beginning of one of the record's names, or of a word in it.
+	MatchPrefix
+	// MatchFuzzy means the query is within a few typos from the beginning of one of the record's names,
+	// or of a word in it.
+	MatchFuzzy
+)
+
+func (m Match) String() string {
+	switch m {
+	case MatchCode:
+		return "code"
+	case MatchExact:
+		return "exact"
+	case MatchPrefix:
+		return "prefix"
+	case MatchFuzzy:
+		return "fuzzy"
+	}
+	return fmt.Sprintf("Match(%d)", m)
+}
+
+// Result is a search hit. Exactly one of City and Airport is set, depending on Kind. Name and Lang are the name
+// that matched the query best and its language; they are empty for code matches. Distance is the number of typos,
+// for fuzzy matches.
+type Result struct {
+	Kind     Kind
+	Code     string
+	City     *lufthansa.City
+	Airport  *lufthansa.Airport
+	Match    Match
+	Name     string
+	Lang     language.Tag
+	Distance int
+	// position is the word index where the name matched, used to rank earlier matches higher.
+	position int
+}
+
+type entry struct {
+	kind   Kind
+	record int
+	name   string
+	lang   language.Tag
+}
+
+// word is a normalized name starting at one of its words, so that a query matching the beginning of any word
+// of the name is a prefix of a word.
+type word struct {
+	text  string
+	runes []rune
+	entry int
+	// position is the index of the word in the name.
+	position int
+}
+
+// trigram is three consecutive runes of a word. The words are padded at the beginning with two gramPad runes, so
+// that their first runes are part of as many trigrams as the others.
+type trigram [3]rune
+
+const gramPad = '\x00'
+
+// posting is an occurrence of a trigram: the word it appears in and its position in the padded word.
+type posting struct {
+	word int
+	at   int
+}
+
+// Index is a search index over airports and cities. It is immutable after creation and safe for concurrent use.
+// The records passed to New are referenced, not copied, by the results.
+//
+// Prefix and exact matches are found by binary search in the words sorted by their text. Fuzzy matches are
+// only looked for among the words sharing enough trigrams with the query to be within the tolerated edit
+// distance from it.
+type Index struct {
+	cities       []lufthansa.City
+	airports     []lufthansa.Airport
+	cityCodes    map[string][]int
+	airportCodes map[string][]int
+	entries      []entry
+	words        []word
+	grams        map[trigram][]posting
+}
+
+var stripMarks = runes.Remove(runes.In(unicode.Mn))
+
+// normalize lowercases s, removes its diacritics and replaces punctuation with single spaces.
+func normalize(s string) string {
+	t := transform.Chain(norm.NFD, stripMarks, norm.NFC)
+	r, _, err := transform.String(t, s)
+	if err != nil {
+		r = s
+	}
+	return strings.Join(strings.FieldsFunc(strings.ToLower(r), func(c rune) bool {
+		return !unicode.IsLetter(c) && !unicode.IsNumber(c)
+	}), " ")
+}
+
+// trigrams calls fn with every trigram of the padded runes and its position.
+func trigrams(rs []rune, fn func(g trigram, at int)) {
+	g := trigram{gramPad, gramPad, gramPad}
+	for i, r := range rs {
+		g[0], g[1], g[2] = g[1], g[2], r
+		fn(g, i)
+	}
+}
+
+func (ix *Index) add(kind Kind, record int, name string, lang language.Tag) {
+	ix.entries = append(ix.entries, entry{kind: kind, record: record, name: name, lang: lang})
+	all := []rune(normalize(name))
+	position := 0
+	for i := range all {
+		if i == 0 || all[i-1] == ' ' {
+			ix.words = append(ix.words, word{text: string(all[i:]), runes: all[i:], entry: len(ix.entries) - 1, position: position})
+			position++
+		}
+	}
+}
+
+// New creates an index over the names of the given cities and airports.
+func New(cities []lufthansa.City, airports []lufthansa.Airport) *Index {
+	ix := &Index{
+		cities:       cities,
+		airports:     airports,
+		cityCodes:    make(map[string][]int),
+		airportCodes: make(map[string][]int),
+		grams:        make(map[trigram][]posting),
+	}
+	for i := range cities {
+		ix.cityCodes[cities[i].CityCode] = append(ix.cityCodes[cities[i].CityCode], i)
+		for lang, name := range cities[i].Names {
+			ix.add(KindCity, i, name, lang)
+		}
+	}
+	for i := range airports {
+		ix.airportCodes[airports[i].AirportCode] = append(ix.airportCodes[airports[i].AirportCode], i)
+		for lang, name := range airports[i].Names {
+			ix.add(KindAirport, i, name, lang)
+		}
+	}
+	sort.Slice(ix.words, func(i, j int) bool { return ix.words[i].text < ix.words[j].text })
+	for i := range ix.words {
+		trigrams(ix.words[i].runes, func(g trigram, at int) {
+			ix.grams[g] = append(ix.grams[g], posting{word: i, at: at})
+		})
+	}
+	return ix
+}
+
+// FromCatalog creates an index over the catalog's cities and airports.
+func FromCatalog(c *catalog.Catalog) *Index {
+	return New(c.Cities(), c.Airports())
+}
+
+// maxTypos returns the edit distance tolerated for a query of the given length.
+func maxTypos(length int) int {
+	switch {
+	case length <= 3:
+		return 0
+	case length <= 6:
+		return 1
+	}
+	return 2
+}
+
+// distance returns the Levenshtein distance between a and b, or max+1 if it is greater than max.
+func distance(a, b []rune, max int) int {
+	if d := len(a) - len(b); d > max || -d > max {
+		return max + 1
+	}
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		rowMin := cur[0]
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if cur[j] < rowMin {
+				rowMin = cur[j]
+			}
+		}
+		if rowMin > max {
+			return max + 1
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
+
+// fuzzyCandidates returns the words whose first len(query) runes may be within typos edits from the query. Every
+// edit changes at most three of the query's padded trigrams, so these runes must contain all but 3*typos of them.
+func (ix *Index) fuzzyCandidates(query []rune, typos int) []int {
+	counts := make(map[int]int)
+	trigrams(query, func(g trigram, _ int) {
+		for _, p := range ix.grams[g] {
+			if p.at < len(query) {
+				counts[p.word]++
+			}
+		}
+	})
+	var candidates []int
+	for w, n := range counts {
+		if n >= len(query)-3*typos {
+			candidates = append(candidates, w)
+		}
+	}
+	return candidates
+}
+
+func isCode(q string) bool {
+	if len(q) != 3 {
+		return false
+	}
+	for _, c := range q {
+		if (c < 'A' || c > 'Z') && (c < 'a' || c > 'z') {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *Result) less(o *Result) bool {
+	if r.Match != o.Match {
+		return r.Match < o.Match
+	}
+	if r.Distance != o.Distance {
+		return r.Distance < o.Distance
+	}
+	if r.position != o.position {
+		return r.position < o.position
+	}
+	if r.Kind != o.Kind {
+		return r.Kind < o.Kind
+	}
+	if len(r.Name) != len(o.Name) {
+		return len(r.Name) < len(o.Name)
+	}
+	if r.Code != o.Code {
+		return r.Code < o.Code
+	}
+	if l, ol := r.Lang.String(), o.Lang.String(); l != ol {
+		return l < ol
+	}
+	return r.Name < o.Name
+}
+
+// Search returns at most limit results for the query, best matches first. Each city or airport appears at most
+// once, with its best matching name. Cities are ranked before airports on otherwise equal matches. A limit
+// less than or equal to zero returns all the results.
+func (ix *Index) Search(query string, limit int) []Result {
+	q := normalize(query)
+	if q == "" {
+		return nil
+	}
+	qr := []rune(q)
+
+	type key struct {
+		kind Kind
+		code string
+	}
+	best := make(map[key]Result)
+	add := func(r Result) {
+		k := key{r.Kind, r.Code}
+		if prev, ok := best[k]; !ok || r.less(&prev) {
+			best[k] = r
+		}
+	}
+
+	if trimmed := strings.TrimSpace(query); isCode(trimmed) {
+		code := strings.ToUpper(trimmed)
+		for _, i := range ix.cityCodes[code] {
+			add(Result{Kind: KindCity, Code: code, City: &ix.cities[i], Match: MatchCode})
+		}
+		for _, i := range ix.airportCodes[code] {
+			add(Result{Kind: KindAirport, Code: code, Airport: &ix.airports[i], Match: MatchCode})
+		}
+	}
+
+	match := func(w *word, m Match, dist int) {
+		e := &ix.entries[w.entry]
+		r := Result{Kind: e.kind, Match: m, Name: e.name, Lang: e.lang, Distance: dist, position: w.position}
+		if r.Kind == KindCity {
+			r.City = &ix.cities[e.record]
+			r.Code = r.City.CityCode
+		} else {
+			r.Airport = &ix.airports[e.record]
+			r.Code = r.Airport.AirportCode
+		}
+		add(r)
+	}
+
+	for i := sort.Search(len(ix.words), func(i int) bool { return ix.words[i].text >= q }); i < len(ix.words); i++ {
+		w := &ix.words[i]
+		if !strings.HasPrefix(w.text, q) {
+			break
+		}
+		if w.position == 0 && w.text == q {
+			match(w, MatchExact, 0)
+		} else {
+			match(w, MatchPrefix, 0)
+		}
+	}
+
+	if typos := maxTypos(len(qr)); typos > 0 {
+		for _, i := range ix.fuzzyCandidates(qr, typos) {
+			w := &ix.words[i]
+			wr := w.runes
+			if len(wr) > len(qr) {
+				wr = wr[:len(qr)]
+			}
+			if d := distance(qr, wr, typos); d > 0 && d <= typos {
+				match(w, MatchFuzzy, d)
+			}
+		}
+	}
+
+	results := make([]Result, 0, len(best))
+	for _, r := range best {
+		results = append(results, r)
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].less(&results[j]) })
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
@@ -0,0 +1,155 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+
+	lufthansa "github.com/tmaxmax/lufthansaapi"
+	"golang.org/x/text/language"
+)
+
+func testIndex(t *testing.T) *Index {
+	t.Helper()
+	cities := []lufthansa.City{
+		{CityCode: "FRA", CountryCode: "DE", Names: map[language.Tag]string{language.English: "Frankfurt", language.French: "Francfort", language.Russian: "Франкфурт"}},
+		{CityCode: "DUS", CountryCode: "DE", Names: map[language.Tag]string{language.English: "Dusseldorf", language.German: "Düsseldorf"}},
+		{CityCode: "MUC", CountryCode: "DE", Names: map[language.Tag]string{language.English: "Munich", language.German: "München"}},
+		{CityCode: "LON", CountryCode: "GB", Names: map[language.Tag]string{language.English: "London"}},
+		{CityCode: "BUH", CountryCode: "RO", Names: map[language.Tag]string{language.English: "Bucharest", language.Romanian: "București"}},
+	}
+	airports := []lufthansa.Airport{
+		{AirportCode: "FRA", CityCode: "FRA", CountryCode: "DE", LocationType: lufthansa.LocationAirport, Names: map[language.Tag]string{language.English: "Frankfurt/Main International Airport"}},
+		{AirportCode: "HHN", CityCode: "HHN", CountryCode: "DE", LocationType: lufthansa.LocationAirport, Names: map[language.Tag]string{language.English: "Frankfurt-Hahn Airport"}},
+		{AirportCode: "DUS", CityCode: "DUS", CountryCode: "DE", LocationType: lufthansa.LocationAirport, Names: map[language.Tag]string{language.English: "Dusseldorf International Airport"}},
+		{AirportCode: "MUC", CityCode: "MUC", CountryCode: "DE", LocationType: lufthansa.LocationAirport, Names: map[language.Tag]string{language.English: "Munich International Airport"}},
+		{AirportCode: "LHR", CityCode: "LON", CountryCode: "GB", LocationType: lufthansa.LocationAirport, Names: map[language.Tag]string{language.English: "London Heathrow Airport"}},
+		{AirportCode: "OTP", CityCode: "BUH", CountryCode: "RO", LocationType: lufthansa.LocationAirport, Names: map[language.Tag]string{language.English: "Henri Coandă International", language.Romanian: "Aeroportul Internațional Henri Coandă"}},
+	}
+	return New(cities, airports)
+}
+
+// code returns the code of the record the word belongs to.
+func recordCode(ix *Index, w *word) string {
+	e := &ix.entries[w.entry]
+	if e.kind == KindCity {
+		return ix.cities[e.record].CityCode
+	}
+	return ix.airports[e.record].AirportCode
+}
+
+func TestNormalize(t *testing.T) {
+	tests := map[string]string{
+		"Düsseldorf":                 "dusseldorf",
+		"Frankfurt/Main  Airport":    "frankfurt main airport",
+		"Aéroport Paris-Orly":        "aeroport paris orly",
+		"Франкфурт":                  "франкфурт",
+		"Henri Coandă International": "henri coanda international",
+	}
+	for input, expected := range tests {
+		if got := normalize(input); got != expected {
+			t.Fatalf("normalize(%q): expected %q, got %q", input, expected, got)
+		}
+	}
+}
+
+func TestIndex_Search(t *testing.T) {
+	ix := testIndex(t)
+
+	tests := []struct {
+		query string
+		kind  Kind
+		code  string
+		match Match
+	}{
+		{"FRA", KindCity, "FRA", MatchCode},
+		{"fra", KindCity, "FRA", MatchCode},
+		{" fra ", KindCity, "FRA", MatchCode},
+		{"Frankfurt", KindCity, "FRA", MatchExact},
+		{"Francfort", KindCity, "FRA", MatchExact},
+		{"Франкфурт", KindCity, "FRA", MatchExact},
+		{"Frankf", KindCity, "FRA", MatchPrefix},
+		{"Frnakfurt", KindCity, "FRA", MatchFuzzy},
+		{"Dusseldorf", KindCity, "DUS", MatchExact},
+		{"muenchen", KindCity, "MUC", MatchFuzzy},
+		{"Heathrow", KindAirport, "LHR", MatchPrefix},
+		{"coanda", KindAirport, "OTP", MatchPrefix},
+	}
+	for _, test := range tests {
+		results := ix.Search(test.query, 5)
+		if len(results) == 0 {
+			t.Fatalf("%q: no results", test.query)
+		}
+		r := results[0]
+		if r.Kind != test.kind || r.Code != test.code || r.Match != test.match {
+			t.Fatalf("%q: expected %s %s (%s match), got %s %s (%s match, name %q)", test.query, test.kind, test.code, test.match, r.Kind, r.Code, r.Match, r.Name)
+		}
+	}
+
+	if results := ix.Search("Xyzzyqwv", 0); len(results) != 0 {
+		t.Fatalf("expected no results, got %v", results)
+	}
+}
+
+func TestIndex_SearchRanking(t *testing.T) {
+	results := testIndex(t).Search("Frankfurt", 0)
+	if len(results) < 3 {
+		t.Fatalf("expected at least 3 results, got %d", len(results))
+	}
+	if results[0].Kind != KindCity || results[1].Match < results[0].Match {
+		t.Fatalf("unexpected ranking %v", results)
+	}
+	seen := make(map[string]bool)
+	for _, r := range results {
+		k := r.Kind.String() + r.Code
+		if seen[k] {
+			t.Fatalf("duplicate result %s", k)
+		}
+		seen[k] = true
+	}
+}
+
+func TestMatch_String(t *testing.T) {
+	if s := MatchFuzzy.String(); s != "fuzzy" {
+		t.Fatalf("expected fuzzy, got %s", s)
+	}
+	if s := Match(42).String(); s != "Match(42)" {
+		t.Fatalf("expected Match(42), got %s", s)
+	}
+}
+
+// TestIndex_SearchMatchesScan checks that the index finds the same records as comparing the query with every word.
+func TestIndex_SearchMatchesScan(t *testing.T) {
+	ix := testIndex(t)
+	queries := []string{"frankfurt", "frnakfurt", "frankfrut", "fankfurt", "munchne", "dusseldrof", "interntional", "heatrow", "bucurest", "coanda", "londn", "aeroport", "xyzw"}
+	for _, q := range queries {
+		qr := []rune(q)
+		typos := maxTypos(len(qr))
+		expected := make(map[string]bool)
+		for i := range ix.words {
+			w := &ix.words[i]
+			wr := w.runes
+			if len(wr) > len(qr) {
+				wr = wr[:len(qr)]
+			}
+			if distance(qr, wr, typos) <= typos {
+				expected[ix.entries[w.entry].kind.String()+" "+recordCode(ix, w)] = true
+			}
+		}
+		got := make(map[string]bool)
+		for _, r := range ix.Search(q, 0) {
+			got[r.Kind.String()+" "+r.Code] = true
+		}
+		if !reflect.DeepEqual(got, expected) {
+			t.Fatalf("%q: expected %v, got %v", q, expected, got)
+		}
+	}
+}
+
+func TestKind_String(t *testing.T) {
+	if s := KindAirport.String(); s != "airport" {
+		t.Fatalf("expected airport, got %s", s)
+	}
+	if s := Kind(7).String(); s != "Kind(7)" {
+		t.Fatalf("expected Kind(7), got %s", s)
+	}
+}
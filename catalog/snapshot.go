@@ -0,0 +1,185 @@
+package catalog
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	lufthansa "github.com/tmaxmax/lufthansaapi"
+)
+
+// SnapshotVersion is the version of the snapshot format written by Catalog.WriteSnapshot.
+const SnapshotVersion = 1
+
+var (
+	// ErrSnapshotVersion is returned when reading a snapshot written in an unsupported format version.
+	ErrSnapshotVersion = errors.New("catalog: snapshot: unsupported version")
+	// ErrCorruptSnapshot is returned when a snapshot's contents don't match its manifest.
+	ErrCorruptSnapshot = errors.New("catalog: snapshot: corrupt snapshot")
+)
+
+// Manifest describes the contents of a snapshot. It is written as the first line of every snapshot.
+type Manifest struct {
+	Version    int       `json:"version"`
+	FetchedAt  time.Time `json:"fetchedAt"`
+	Countries  int       `json:"countries"`
+	Cities     int       `json:"cities"`
+	Airports   int       `json:"airports"`
+	LHOperated int       `json:"lhOperated"`
+}
+
+// Record types used in snapshots.
+const (
+	recordCountry = "country"
+	recordCity    = "city"
+	recordAirport = "airport"
+)
+
+type snapshotRecord struct {
+	Type       string          `json:"type"`
+	LHOperated bool            `json:"lhOperated,omitempty"`
+	Record     json.RawMessage `json:"record"`
+}
+
+// Manifest returns the manifest that describes the catalog's snapshot.
+func (c *Catalog) Manifest() Manifest {
+	return Manifest{
+		Version:    SnapshotVersion,
+		FetchedAt:  c.FetchedAt,
+		Countries:  len(c.countries),
+		Cities:     len(c.cities),
+		Airports:   len(c.airports),
+		LHOperated: len(c.lhOperated),
+	}
+}
+
+// WriteSnapshot writes the catalog to w as gzip-compressed JSON lines: the first line is the manifest, and every
+// following line is a country, city or airport record. The output is deterministic for a given catalog.
+func (c *Catalog) WriteSnapshot(w io.Writer) error {
+	zw := gzip.NewWriter(w)
+	enc := json.NewEncoder(zw)
+
+	if err := enc.Encode(c.Manifest()); err != nil {
+		return err
+	}
+	write := func(typ string, lhOperated bool, v interface{}) error {
+		data, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		return enc.Encode(snapshotRecord{Type: typ, LHOperated: lhOperated, Record: data})
+	}
+	for i := range c.countries {
+		if err := write(recordCountry, false, &c.countries[i]); err != nil {
+			return err
+		}
+	}
+	for i := range c.cities {
+		if err := write(recordCity, false, &c.cities[i]); err != nil {
+			return err
+		}
+	}
+	for i := range c.airports {
+		a := &c.airports[i]
+		if err := write(recordAirport, c.lhOperated[a.AirportCode], a); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// ReadSnapshot reads a snapshot written by Catalog.WriteSnapshot and builds a catalog from it.
+func ReadSnapshot(r io.Reader) (*Catalog, error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+
+	dec := json.NewDecoder(bufio.NewReader(zr))
+	var m Manifest
+	if err = dec.Decode(&m); err != nil {
+		return nil, err
+	}
+	if m.Version != SnapshotVersion {
+		return nil, fmt.Errorf("%w: %d", ErrSnapshotVersion, m.Version)
+	}
+
+	countries := make([]lufthansa.Country, 0, m.Countries)
+	cities := make([]lufthansa.City, 0, m.Cities)
+	airports := make([]lufthansa.Airport, 0, m.Airports)
+	lhOperated := make([]string, 0, m.LHOperated)
+	for {
+		var rec snapshotRecord
+		if err = dec.Decode(&rec); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		switch rec.Type {
+		case recordCountry:
+			countries = append(countries, lufthansa.Country{})
+			err = json.Unmarshal(rec.Record, &countries[len(countries)-1])
+		case recordCity:
+			cities = append(cities, lufthansa.City{})
+			err = json.Unmarshal(rec.Record, &cities[len(cities)-1])
+		case recordAirport:
+			airports = append(airports, lufthansa.Airport{})
+			a := &airports[len(airports)-1]
+			if err = json.Unmarshal(rec.Record, a); err == nil && rec.LHOperated {
+				lhOperated = append(lhOperated, a.AirportCode)
+			}
+		default:
+			err = fmt.Errorf("%w: unknown record type %q", ErrCorruptSnapshot, rec.Type)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(countries) != m.Countries || len(cities) != m.Cities || len(airports) != m.Airports || len(lhOperated) != m.LHOperated {
+		return nil, fmt.Errorf("%w: record counts don't match the manifest", ErrCorruptSnapshot)
+	}
+	return New(m.FetchedAt, countries, cities, airports, lhOperated), nil
+}
+
+// SaveFile writes the catalog's snapshot to the file at the given path. The file is replaced atomically,
+// so readers never see a partially written snapshot.
+func (c *Catalog) SaveFile(path string) error {
+	f, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if err = f.Chmod(0644); err != nil {
+		f.Close()
+		return err
+	}
+	if err = c.WriteSnapshot(f); err != nil {
+		f.Close()
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// LoadFile reads the snapshot from the file at the given path.
+func LoadFile(path string) (*Catalog, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return ReadSnapshot(f)
+}
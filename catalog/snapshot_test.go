@@ -0,0 +1,110 @@
+package catalog
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSnapshot_RoundTrip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "catalog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	c := testCatalog()
+	path := filepath.Join(dir, "reference.jsonl.gz")
+	if err = c.SaveFile(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !loaded.FetchedAt.Equal(c.FetchedAt) {
+		t.Fatalf("expected fetch time %s, got %s", c.FetchedAt, loaded.FetchedAt)
+	}
+	if !reflect.DeepEqual(loaded.Countries(), c.Countries()) {
+		t.Fatalf("countries differ:\n%v\n%v", c.Countries(), loaded.Countries())
+	}
+	if !reflect.DeepEqual(loaded.Cities(), c.Cities()) {
+		t.Fatalf("cities differ:\n%v\n%v", c.Cities(), loaded.Cities())
+	}
+	if !reflect.DeepEqual(loaded.Airports(), c.Airports()) {
+		t.Fatalf("airports differ:\n%v\n%v", c.Airports(), loaded.Airports())
+	}
+	if loaded.Manifest() != c.Manifest() {
+		t.Fatalf("expected manifest %+v, got %+v", c.Manifest(), loaded.Manifest())
+	}
+}
+
+func TestSnapshot_Deterministic(t *testing.T) {
+	var a, b bytes.Buffer
+	if err := testCatalog().WriteSnapshot(&a); err != nil {
+		t.Fatal(err)
+	}
+	if err := testCatalog().WriteSnapshot(&b); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(a.Bytes(), b.Bytes()) {
+		t.Fatal("expected identical snapshots for identical catalogs")
+	}
+}
+
+func TestSnapshot_UnknownLHOperated(t *testing.T) {
+	base := testCatalog()
+	// the LH operated list may contain airports that weren't returned by the references endpoint
+	c := New(base.FetchedAt, base.Countries(), base.Cities(), base.Airports(), []string{"FRA", "XXX"})
+	if got := c.Manifest().LHOperated; got != 1 {
+		t.Fatalf("expected 1 LH operated airport, got %d", got)
+	}
+
+	var buf bytes.Buffer
+	if err := c.WriteSnapshot(&buf); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := ReadSnapshot(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !loaded.LHOperated("FRA") || loaded.LHOperated("XXX") {
+		t.Fatal("expected only FRA to be LH operated")
+	}
+}
+
+func TestReadSnapshot_Corrupt(t *testing.T) {
+	var buf bytes.Buffer
+	if err := testCatalog().WriteSnapshot(&buf); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := gzip.NewReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// drop the last record, so the manifest reports more airports than there are
+	lines := bytes.SplitAfter(bytes.TrimSuffix(data, []byte("\n")), []byte("\n"))
+	var truncated bytes.Buffer
+	zw := gzip.NewWriter(&truncated)
+	if _, err = zw.Write(bytes.Join(lines[:len(lines)-1], nil)); err != nil {
+		t.Fatal(err)
+	}
+	if err = zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ReadSnapshot(&truncated); !errors.Is(err, ErrCorruptSnapshot) {
+		t.Fatalf("expected ErrCorruptSnapshot, got %v", err)
+	}
+}
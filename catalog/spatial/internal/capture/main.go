@@ -0,0 +1,55 @@
+// Command capture requests the airports nearest to the centre of Frankfurt from the Lufthansa API and writes the
+// fields the spatial tests compare as XML. It is run by go generate in the spatial package; the API credentials
+// are read from the LOA_ID and LOA_SECRET environment variables.
+package main
+
+import (
+	"context"
+	"encoding/xml"
+	"flag"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	lufthansa "github.com/tmaxmax/lufthansaapi"
+)
+
+type nearest struct {
+	XMLName  xml.Name `xml:"NearestAirportResource" json:"-"`
+	Airports []struct {
+		AirportCode  string  `xml:"AirportCode" json:"AirportCode"`
+		Latitude     float64 `xml:"Position>Coordinate>Latitude" json:"Position.Coordinate.Latitude"`
+		Longitude    float64 `xml:"Position>Coordinate>Longitude" json:"Position.Coordinate.Longitude"`
+		LocationType string  `xml:"LocationType" json:"LocationType"`
+		Distance     float64 `xml:"Distance>Value" json:"Distance.Value"`
+	} `xml:"Airports>Airport" json:"NearestAirportResource.Airports.Airport"`
+}
+
+func main() {
+	out := flag.String("o", "testdata/nearest_50.111,8.682.xml", "output file")
+	flag.Parse()
+
+	ctx := context.Background()
+	api, err := lufthansa.NewAPI(ctx, os.Getenv("LOA_ID"), os.Getenv("LOA_SECRET"), 5, 1000)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	n := &nearest{}
+	if err = api.Do(ctx, http.MethodGet, "mds-references/airports/nearest/50.111,8.682", url.Values{"lang": {"en"}}, n); err != nil {
+		log.Fatalln(err)
+	}
+	data, err := xml.MarshalIndent(n, "", "\t")
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if err = os.MkdirAll(filepath.Dir(*out), 0755); err != nil {
+		log.Fatalln(err)
+	}
+	if err = ioutil.WriteFile(*out, append(data, '\n'), 0644); err != nil {
+		log.Fatalln(err)
+	}
+	log.Printf("wrote %d airports to %s", len(n.Airports), *out)
+}
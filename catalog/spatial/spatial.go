@@ -0,0 +1,216 @@
+// Package spatial answers nearest airport queries offline, using a k-d tree over the airports' positions.
+// Unlike the nearest airports endpoint, it doesn't use any quota and isn't limited to five results.
+package spatial
+
+//go:generate go run ./internal/capture -o testdata/nearest_50.111,8.682.xml
+
+import (
+	"container/heap"
+	"math"
+	"sort"
+
+	lufthansa "github.com/tmaxmax/lufthansaapi"
+	"github.com/tmaxmax/lufthansaapi/catalog"
+)
+
+// point is a position on the unit sphere. The euclidean (chord) distance between two points grows monotonically
+// with their great-circle distance, so the tree can work in three dimensional space without distortions.
+type point [3]float64
+
+func toPoint(c lufthansa.Coordinate) point {
+	lat, lon := c.Latitude*math.Pi/180, c.Longitude*math.Pi/180
+	return point{math.Cos(lat) * math.Cos(lon), math.Cos(lat) * math.Sin(lon), math.Sin(lat)}
+}
+
+func (p point) dist2(o point) float64 {
+	dx, dy, dz := p[0]-o[0], p[1]-o[1], p[2]-o[2]
+	return dx*dx + dy*dy + dz*dz
+}
+
+// chord2 returns the squared chord length corresponding to a great-circle distance in kilometres.
+func chord2(km float64) float64 {
+	if km >= math.Pi*lufthansa.EarthRadius {
+		return 4
+	}
+	c := 2 * math.Sin(km/(2*lufthansa.EarthRadius))
+	return c * c
+}
+
+// Filter restricts the airports returned by the queries. Zero valued fields don't restrict the results.
+type Filter struct {
+	LocationTypes []lufthansa.LocationType
+	LHOperated    bool
+}
+
+func (f *Filter) matches(ix *Index, i int) bool {
+	if f == nil {
+		return true
+	}
+	a := &ix.airports[i]
+	if f.LHOperated && (ix.lhOperated == nil || !ix.lhOperated(a.AirportCode)) {
+		return false
+	}
+	if len(f.LocationTypes) == 0 {
+		return true
+	}
+	for _, lt := range f.LocationTypes {
+		if a.LocationType == lt {
+			return true
+		}
+	}
+	return false
+}
+
+// Result is an airport found by a query, together with its great-circle distance from the queried position.
+type Result struct {
+	Airport  *lufthansa.Airport
+	Distance float64
+}
+
+// Index is a k-d tree over airport positions. It is immutable after creation and safe for concurrent use.
+// The airports passed to New are referenced, not copied, by the results.
+type Index struct {
+	airports   []lufthansa.Airport
+	lhOperated func(airportCode string) bool
+	points     []point
+	// tree holds the airport indices arranged as an implicit k-d tree: the median of every range is its root,
+	// split on the axis given by the depth.
+	tree []int
+}
+
+// New builds an index over the given airports. lhOperated reports whether an airport is operated by Lufthansa
+// and is used by Filter.LHOperated; it may be nil if that filter isn't needed.
+func New(airports []lufthansa.Airport, lhOperated func(airportCode string) bool) *Index {
+	ix := &Index{
+		airports:   airports,
+		lhOperated: lhOperated,
+		points:     make([]point, len(airports)),
+		tree:       make([]int, len(airports)),
+	}
+	for i := range airports {
+		ix.points[i] = toPoint(airports[i].Position)
+		ix.tree[i] = i
+	}
+	ix.build(0, len(ix.tree), 0)
+	return ix
+}
+
+// FromCatalog builds an index over the catalog's airports.
+func FromCatalog(c *catalog.Catalog) *Index {
+	return New(c.Airports(), c.LHOperated)
+}
+
+func (ix *Index) build(lo, hi, depth int) {
+	if hi-lo <= 1 {
+		return
+	}
+	axis := depth % 3
+	sub := ix.tree[lo:hi]
+	sort.Slice(sub, func(i, j int) bool {
+		return ix.points[sub[i]][axis] < ix.points[sub[j]][axis]
+	})
+	mid := (lo + hi) / 2
+	ix.build(lo, mid, depth+1)
+	ix.build(mid+1, hi, depth+1)
+}
+
+// visit walks the subtree in [lo, hi), calling fn for every airport whose squared chord distance to q is within
+// the bound returned by limit. limit is called again after every fn call, so the bound can shrink while walking.
+func (ix *Index) visit(q point, lo, hi, depth int, limit func() float64, fn func(i int, d2 float64)) {
+	if lo >= hi {
+		return
+	}
+	mid := (lo + hi) / 2
+	i := ix.tree[mid]
+	if d2 := q.dist2(ix.points[i]); d2 <= limit() {
+		fn(i, d2)
+	}
+	axis := depth % 3
+	diff := q[axis] - ix.points[i][axis]
+	nearLo, nearHi, farLo, farHi := lo, mid, mid+1, hi
+	if diff > 0 {
+		nearLo, nearHi, farLo, farHi = mid+1, hi, lo, mid
+	}
+	ix.visit(q, nearLo, nearHi, depth+1, limit, fn)
+	if diff*diff <= limit() {
+		ix.visit(q, farLo, farHi, depth+1, limit, fn)
+	}
+}
+
+type candidate struct {
+	index int
+	d2    float64
+}
+
+// maxHeap keeps the k nearest candidates found so far, the farthest one on top.
+type maxHeap []candidate
+
+func (h maxHeap) Len() int            { return len(h) }
+func (h maxHeap) Less(i, j int) bool  { return h[i].d2 > h[j].d2 }
+func (h maxHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *maxHeap) Push(x interface{}) { *h = append(*h, x.(candidate)) }
+func (h *maxHeap) Pop() interface{} {
+	old := *h
+	c := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return c
+}
+
+func (ix *Index) results(c lufthansa.Coordinate, candidates []candidate) []Result {
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].d2 != candidates[j].d2 {
+			return candidates[i].d2 < candidates[j].d2
+		}
+		return ix.airports[candidates[i].index].AirportCode < ix.airports[candidates[j].index].AirportCode
+	})
+	ret := make([]Result, len(candidates))
+	for i, cd := range candidates {
+		a := &ix.airports[cd.index]
+		ret[i] = Result{Airport: a, Distance: c.DistanceTo(a.Position)}
+	}
+	return ret
+}
+
+// Nearest returns the k airports nearest to the given position that match the filter, nearest first.
+// The filter may be nil.
+func (ix *Index) Nearest(c lufthansa.Coordinate, k int, f *Filter) []Result {
+	if k <= 0 {
+		return nil
+	}
+	h := make(maxHeap, 0, k+1)
+	limit := func() float64 {
+		if len(h) < k {
+			return math.Inf(1)
+		}
+		return h[0].d2
+	}
+	ix.visit(toPoint(c), 0, len(ix.tree), 0, limit, func(i int, d2 float64) {
+		if !f.matches(ix, i) {
+			return
+		}
+		heap.Push(&h, candidate{i, d2})
+		if len(h) > k {
+			heap.Pop(&h)
+		}
+	})
+	return ix.results(c, h)
+}
+
+// Within returns the airports matching the filter within the given great-circle distance, in kilometres,
+// from the position, nearest first. The filter may be nil.
+func (ix *Index) Within(c lufthansa.Coordinate, radius float64, f *Filter) []Result {
+	// the bound is slightly inflated so rounding errors don't drop airports right on the circle;
+	// the exact distances are checked below
+	bound := chord2(radius)*(1+1e-9) + 1e-12
+	var found []candidate
+	ix.visit(toPoint(c), 0, len(ix.tree), 0, func() float64 { return bound }, func(i int, d2 float64) {
+		if f.matches(ix, i) {
+			found = append(found, candidate{i, d2})
+		}
+	})
+	results := ix.results(c, found)
+	for len(results) > 0 && results[len(results)-1].Distance > radius {
+		results = results[:len(results)-1]
+	}
+	return results
+}
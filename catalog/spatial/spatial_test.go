@@ -0,0 +1,196 @@
+package spatial
+
+import (
+	"encoding/xml"
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+
+	lufthansa "github.com/tmaxmax/lufthansaapi"
+)
+
+// serverFixture is a response of GET /mds-references/airports/nearest/50.111,8.682?lang=en captured from the live
+// API by running go generate in this package, with the LOA_ID and LOA_SECRET environment variables set to the API
+// credentials.
+const serverFixture = "testdata/nearest_50.111,8.682.xml"
+
+var frankfurt = lufthansa.Coordinate{Latitude: 50.111, Longitude: 8.682}
+
+type nearestFixture struct {
+	Airports []struct {
+		AirportCode  string                 `xml:"AirportCode"`
+		Latitude     float64                `xml:"Position>Coordinate>Latitude"`
+		Longitude    float64                `xml:"Position>Coordinate>Longitude"`
+		LocationType lufthansa.LocationType `xml:"LocationType"`
+		Distance     float64                `xml:"Distance>Value"`
+	} `xml:"Airports>Airport"`
+}
+
+// testAirports returns a few airports of Europe and elsewhere, all of them operated by Lufthansa except for the
+// Frankfurt central station.
+func testAirports() []lufthansa.Airport {
+	records := []struct {
+		code     string
+		lat, lon float64
+		typ      lufthansa.LocationType
+	}{
+		{"BER", 52.3667, 13.5033, lufthansa.LocationAirport},
+		{"CDG", 49.0097, 2.5479, lufthansa.LocationAirport},
+		{"DUS", 51.2895, 6.7668, lufthansa.LocationAirport},
+		{"EWR", 40.6925, -74.1687, lufthansa.LocationAirport},
+		{"FRA", 50.0333, 8.5706, lufthansa.LocationAirport},
+		{"HAM", 53.6304, 9.9882, lufthansa.LocationAirport},
+		{"JFK", 40.6398, -73.7789, lufthansa.LocationAirport},
+		{"LHR", 51.47, -0.4543, lufthansa.LocationAirport},
+		{"MUC", 48.3538, 11.7861, lufthansa.LocationAirport},
+		{"ORY", 48.7233, 2.3794, lufthansa.LocationAirport},
+		{"OTP", 44.5711, 26.085, lufthansa.LocationAirport},
+		{"SYD", -33.9461, 151.1772, lufthansa.LocationAirport},
+		{"VIE", 48.1103, 16.5697, lufthansa.LocationAirport},
+		{"ZRB", 50.1069, 8.6633, lufthansa.LocationRailwayStation},
+		{"ZRH", 47.4647, 8.5492, lufthansa.LocationAirport},
+	}
+	airports := make([]lufthansa.Airport, len(records))
+	for i, r := range records {
+		airports[i] = lufthansa.Airport{
+			AirportCode:  r.code,
+			Position:     lufthansa.Coordinate{Latitude: r.lat, Longitude: r.lon},
+			LocationType: r.typ,
+		}
+	}
+	return airports
+}
+
+func testIndex() (*Index, []lufthansa.Airport) {
+	airports := testAirports()
+	return New(airports, func(code string) bool { return code != "ZRB" }), airports
+}
+
+// bruteForce returns the codes of the airports sorted by their distance to the position.
+func bruteForce(airports []lufthansa.Airport, c lufthansa.Coordinate) []string {
+	sorted := append([]lufthansa.Airport(nil), airports...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return c.DistanceTo(sorted[i].Position) < c.DistanceTo(sorted[j].Position)
+	})
+	codes := make([]string, len(sorted))
+	for i := range sorted {
+		codes[i] = sorted[i].AirportCode
+	}
+	return codes
+}
+
+func TestIndex_Nearest(t *testing.T) {
+	ix, airports := testIndex()
+
+	results := ix.Nearest(frankfurt, 2, nil)
+	if len(results) != 2 || results[0].Airport.AirportCode != "ZRB" || results[1].Airport.AirportCode != "FRA" {
+		t.Fatalf("unexpected results %v", results)
+	}
+	// Frankfurt airport is about 12km south-west of the city centre
+	if math.Abs(results[1].Distance-12) > 1 {
+		t.Fatalf("expected FRA about 12km away, got %f", results[1].Distance)
+	}
+
+	for _, q := range []lufthansa.Coordinate{frankfurt, {Latitude: 40.7, Longitude: -74}, {Latitude: -33.9, Longitude: 151.2}, {Latitude: 44.4, Longitude: 26.1}} {
+		expected := bruteForce(airports, q)
+		results := ix.Nearest(q, len(airports), nil)
+		if len(results) != len(expected) {
+			t.Fatalf("query %v: expected %d results, got %d", q, len(expected), len(results))
+		}
+		for i, r := range results {
+			if r.Airport.AirportCode != expected[i] {
+				t.Fatalf("query %v, result %d: expected %s, got %s", q, i, expected[i], r.Airport.AirportCode)
+			}
+		}
+	}
+}
+
+// TestIndex_NearestAgreesWithServer indexes the airports of a captured response and checks that the index ranks
+// them like the server did, at the same distances.
+func TestIndex_NearestAgreesWithServer(t *testing.T) {
+	data, err := ioutil.ReadFile(serverFixture)
+	if err != nil {
+		t.Fatalf("%v: capture the response by running go generate in this package", err)
+	}
+	f := &nearestFixture{}
+	if err = xml.Unmarshal(data, f); err != nil {
+		t.Fatal(err)
+	}
+	if len(f.Airports) == 0 {
+		t.Fatal("the captured response has no airports")
+	}
+
+	airports := make([]lufthansa.Airport, len(f.Airports))
+	expected := make([]string, len(f.Airports))
+	for i, a := range f.Airports {
+		airports[i] = lufthansa.Airport{
+			AirportCode:  a.AirportCode,
+			Position:     lufthansa.Coordinate{Latitude: a.Latitude, Longitude: a.Longitude},
+			LocationType: a.LocationType,
+		}
+		expected[i] = a.AirportCode
+	}
+
+	results := New(airports, nil).Nearest(frankfurt, len(airports), nil)
+	got := make([]string, len(results))
+	for i, r := range results {
+		got[i] = r.Airport.AirportCode
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected ranking %v, got %v", expected, got)
+	}
+	for i, r := range results {
+		// the server rounds the distances to whole kilometres
+		if d := f.Airports[i].Distance; math.Abs(r.Distance-d) > 1 {
+			t.Fatalf("%s: expected a distance of %fkm, got %f", r.Airport.AirportCode, d, r.Distance)
+		}
+	}
+}
+
+func TestIndex_Filter(t *testing.T) {
+	ix, _ := testIndex()
+
+	results := ix.Nearest(frankfurt, 2, &Filter{LocationTypes: []lufthansa.LocationType{lufthansa.LocationAirport}, LHOperated: true})
+	if len(results) != 2 || results[0].Airport.AirportCode != "FRA" || results[1].Airport.AirportCode != "DUS" {
+		t.Fatalf("unexpected results %v", results)
+	}
+
+	within := ix.Within(frankfurt, 200, nil)
+	if len(within) != 3 || within[2].Airport.AirportCode != "DUS" {
+		t.Fatalf("unexpected results %v", within)
+	}
+	if len(ix.Within(frankfurt, 1, nil)) != 0 {
+		t.Fatal("expected no airports within 1km")
+	}
+}
+
+func TestIndex_MatchesBruteForce(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	airports := make([]lufthansa.Airport, 2000)
+	for i := range airports {
+		airports[i].Position = lufthansa.Coordinate{Latitude: r.Float64()*180 - 90, Longitude: r.Float64()*360 - 180}
+	}
+	ix := New(airports, nil)
+
+	for n := 0; n < 50; n++ {
+		q := lufthansa.Coordinate{Latitude: r.Float64()*180 - 90, Longitude: r.Float64()*360 - 180}
+		distances := make([]float64, len(airports))
+		for i := range airports {
+			distances[i] = q.DistanceTo(airports[i].Position)
+		}
+		sort.Float64s(distances)
+
+		results := ix.Nearest(q, 10, nil)
+		for i := range results {
+			if math.Abs(results[i].Distance-distances[i]) > 1e-6 {
+				t.Fatalf("query %v, result %d: expected distance %f, got %f", q, i, distances[i], results[i].Distance)
+			}
+		}
+		if within := ix.Within(q, distances[20], nil); len(within) < 21 {
+			t.Fatalf("query %v: expected at least 21 results within %fkm, got %d", q, distances[20], len(within))
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package lufthansa
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+
+	"github.com/tmaxmax/lufthansaapi/pkg/circuitbreaker"
+	"github.com/tmaxmax/lufthansaapi/pkg/ratelimithttp"
+)
+
+// breakerOutcome classifies the result of a request made with ctx for the circuit breaker. Server errors (5xx),
+// timeouts of the HTTP client and the network, and connection errors are failures; requests canceled by the caller,
+// whose deadline passed or which were stopped by the rate limiters say nothing about the API's health and are ignored.
+func breakerOutcome(ctx context.Context, err error) circuitbreaker.Outcome {
+	var (
+		ue *unknownError
+		ne net.Error
+	)
+	switch {
+	case err == nil || err == errNotModified:
+		return circuitbreaker.Success
+	case ctx.Err() != nil, errors.Is(err, context.Canceled), errors.Is(err, ratelimithttp.ErrQuotaExhausted), errors.Is(err, circuitbreaker.ErrOpen):
+		return circuitbreaker.Ignored
+	case errors.As(err, &ue):
+		if ue.statusCode >= 500 {
+			return circuitbreaker.ServerError
+		}
+		return circuitbreaker.Success
+	case errors.As(err, &ne):
+		if ne.Timeout() {
+			return circuitbreaker.Timeout
+		}
+		return circuitbreaker.Failure
+	case errors.Is(err, context.DeadlineExceeded):
+		return circuitbreaker.Timeout
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		// the connection was closed before the whole response was sent
+		return circuitbreaker.Failure
+	default:
+		// the API responded, and the error is about the response
+		return circuitbreaker.Success
+	}
+}
+
+// breakerBody reports the failures while reading a response body to the circuit breaker. The request's outcome
+// is reported as soon as the response headers arrive, so a body that is never read doesn't hold the breaker's
+// half-open trials; a failure while reading it is then counted on its own.
+type breakerBody struct {
+	io.ReadCloser
+	ctx      context.Context
+	breaker  *circuitbreaker.Breaker
+	reported bool
+}
+
+func (b *breakerBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err != nil && err != io.EOF && !b.reported {
+		b.reported = true
+		b.breaker.Report(breakerOutcome(b.ctx, err))
+	}
+	return n, err
+}
+
+// CircuitBreaker returns the API's circuit breaker, or nil if it was disabled. Use it to check its state.
+func (a *API) CircuitBreaker() *circuitbreaker.Breaker {
+	return a.breaker
+}
+
+// WithCircuitBreaker replaces the API's circuit breaker, which by default opens after 5 consecutive failed
+// requests and lets a trial request through after 30 seconds. Requests fail with an error matching
+// circuitbreaker.ErrOpen while it is open. Server errors (5xx), timeouts and connection errors are counted as
+// failures, including those that happen while reading the response; server errors and timeouts are reported as
+// such, so they can have their own thresholds. The context of a request being canceled or reaching its deadline
+// isn't counted. Create the breaker with your own thresholds and state change hook:
+//
+//	lufthansa.WithCircuitBreaker(circuitbreaker.New(circuitbreaker.Config{
+//		Failures:    5,
+//		Timeouts:    3,
+//		OpenTimeout: time.Minute,
+//		OnStateChange: func(from, to circuitbreaker.State) {
+//			log.Printf("Lufthansa API circuit %s -> %s", from, to)
+//		},
+//	}))
+//
+// Passing nil disables the circuit breaker.
+func WithCircuitBreaker(b *circuitbreaker.Breaker) Option {
+	return func(a *API) {
+		a.breaker = b
+	}
+}
@@ -0,0 +1,87 @@
+package lufthansa
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/tmaxmax/lufthansaapi/pkg/circuitbreaker"
+)
+
+func TestBreakerOutcome(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/error":
+			w.WriteHeader(http.StatusInternalServerError)
+		case "/throttled":
+			w.WriteHeader(http.StatusTooManyRequests)
+		case "/slow":
+			time.Sleep(200 * time.Millisecond)
+		}
+	}))
+	defer srv.Close()
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+
+	get := func(ctx context.Context, client *http.Client, url string) error {
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		res, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		return decodeErrors(res)
+	}
+	short, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		client   *http.Client
+		url      string
+		expected circuitbreaker.Outcome
+	}{
+		{"success", context.Background(), http.DefaultClient, srv.URL + "/", circuitbreaker.Success},
+		{"server error", context.Background(), http.DefaultClient, srv.URL + "/error", circuitbreaker.ServerError},
+		{"client error", context.Background(), http.DefaultClient, srv.URL + "/throttled", circuitbreaker.Success},
+		{"client timeout", context.Background(), &http.Client{Timeout: 20 * time.Millisecond}, srv.URL + "/slow", circuitbreaker.Timeout},
+		{"caller deadline", short, http.DefaultClient, srv.URL + "/slow", circuitbreaker.Ignored},
+		{"connection refused", context.Background(), http.DefaultClient, closed.URL, circuitbreaker.Failure},
+	}
+	for _, test := range tests {
+		if o := breakerOutcome(test.ctx, get(test.ctx, test.client, test.url)); o != test.expected {
+			t.Errorf("%s: expected outcome %d, got %d", test.name, test.expected, o)
+		}
+	}
+}
+
+func TestBreakerBody(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("partial"))
+		w.(http.Flusher).Flush()
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer srv.Close()
+
+	b := circuitbreaker.New(circuitbreaker.Config{Failures: 1})
+	done, err := b.Allow()
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := (&http.Client{Timeout: 50 * time.Millisecond}).Get(srv.URL)
+	done(breakerOutcome(context.Background(), err))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := &breakerBody{ReadCloser: res.Body, ctx: context.Background(), breaker: b}
+	if _, err = ioutil.ReadAll(body); err == nil {
+		t.Fatal("expected the body read to time out")
+	}
+	body.Close()
+	if s := b.State(); s != circuitbreaker.Open {
+		t.Fatalf("expected the timeout while reading the body to open the breaker, got state %s", s)
+	}
+}
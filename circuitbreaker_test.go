@@ -0,0 +1,16 @@
+package lufthansa_test
+
+import (
+	"testing"
+
+	"github.com/tmaxmax/lufthansaapi/pkg/circuitbreaker"
+)
+
+func TestAPI_CircuitBreaker(t *testing.T) {
+	if _, err := api.FetchCountry(ctx, "DE", nil); err != nil {
+		t.Fatal(err)
+	}
+	if s := api.CircuitBreaker().State(); s != circuitbreaker.Closed {
+		t.Fatalf("expected the circuit to be closed after a successful request, got %s", s)
+	}
+}
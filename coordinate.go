@@ -0,0 +1,47 @@
+package lufthansa
+
+import (
+	"fmt"
+	"math"
+)
+
+// EarthRadius is the mean radius of the Earth in kilometres, used for the great-circle computations.
+const EarthRadius = 6371.0088
+
+// Coordinate is a geographic position, in decimal degrees.
+type Coordinate struct {
+	Latitude  float64
+	Longitude float64
+}
+
+func radians(deg float64) float64 {
+	return deg * math.Pi / 180
+}
+
+func degrees(rad float64) float64 {
+	return rad * 180 / math.Pi
+}
+
+// DistanceTo returns the great-circle distance to o in kilometres, computed with the haversine formula.
+func (c Coordinate) DistanceTo(o Coordinate) float64 {
+	lat1, lat2 := radians(c.Latitude), radians(o.Latitude)
+	dLat, dLon := lat2-lat1, radians(o.Longitude-c.Longitude)
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * EarthRadius * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// BearingTo returns the initial bearing (forward azimuth) of the great-circle path to o,
+// in degrees clockwise from north, in the range [0, 360).
+func (c Coordinate) BearingTo(o Coordinate) float64 {
+	lat1, lat2 := radians(c.Latitude), radians(o.Latitude)
+	dLon := radians(o.Longitude - c.Longitude)
+
+	y := math.Sin(dLon) * math.Cos(lat2)
+	x := math.Cos(lat1)*math.Sin(lat2) - math.Sin(lat1)*math.Cos(lat2)*math.Cos(dLon)
+	return math.Mod(degrees(math.Atan2(y, x))+360, 360)
+}
+
+func (c Coordinate) String() string {
+	return fmt.Sprintf("%.4f, %.4f", c.Latitude, c.Longitude)
+}
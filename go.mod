@@ -1,10 +1,10 @@
 module github.com/tmaxmax/lufthansaapi
 
-go 1.14
+go 1.16
 
 require (
 	github.com/gabriel-vasile/mimetype v1.1.1
 	github.com/tmaxmax/json v0.4.0
 	golang.org/x/text v0.3.3
-	golang.org/x/time v0.0.0-20200630173020-3af7569d3a1e
+	golang.org/x/time v0.3.0
 )
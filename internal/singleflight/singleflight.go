@@ -0,0 +1,195 @@
+// Package singleflight provides a duplicate call suppression mechanism: concurrent calls with the same key
+// wait for the first one to finish and share its result.
+package singleflight
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type call struct {
+	wg   sync.WaitGroup
+	val  interface{}
+	err  error
+	dups int
+}
+
+// contextCall is an in-flight call made by DoContext. It is canceled once all its callers gave up waiting.
+type contextCall struct {
+	done chan struct{}
+	val  interface{}
+	err  error
+	dups int
+	refs int
+	// deadlines holds the deadlines of the callers still waiting, and unbounded the number of those without one.
+	deadlines []time.Time
+	unbounded int
+	// ctxDone is closed when the call's context is canceled, and ctxErr is the context's error.
+	ctxDone chan struct{}
+	ctxErr  error
+}
+
+// cancel cancels the call's context with the given error, if it isn't canceled already. The caller must hold the
+// group's lock.
+func (c *contextCall) cancel(err error) {
+	if c.ctxErr == nil {
+		c.ctxErr = err
+		close(c.ctxDone)
+	}
+}
+
+// join registers a caller with the given context. The caller must hold the group's lock.
+func (c *contextCall) join(ctx context.Context) {
+	c.refs++
+	if deadline, ok := ctx.Deadline(); ok {
+		c.deadlines = append(c.deadlines, deadline)
+	} else {
+		c.unbounded++
+	}
+}
+
+// leave unregisters a caller that stopped waiting. The caller must hold the group's lock.
+func (c *contextCall) leave(ctx context.Context) {
+	c.refs--
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		c.unbounded--
+		return
+	}
+	for i, d := range c.deadlines {
+		if d.Equal(deadline) {
+			c.deadlines = append(c.deadlines[:i], c.deadlines[i+1:]...)
+			return
+		}
+	}
+}
+
+// Group is a namespace in which calls are deduplicated. The zero value is ready to use.
+type Group struct {
+	mu sync.Mutex
+	m  map[string]*call
+	mc map[string]*contextCall
+}
+
+// Do executes fn, making sure that only one execution is in-flight for a given key at a time. If a duplicate call
+// comes in, it waits for the original one to complete and receives the same results. The shared return value
+// reports whether the results were given to multiple callers.
+func (g *Group) Do(key string, fn func() (interface{}, error)) (v interface{}, err error, shared bool) {
+	g.mu.Lock()
+	if g.m == nil {
+		g.m = make(map[string]*call)
+	}
+	if c, ok := g.m[key]; ok {
+		c.dups++
+		g.mu.Unlock()
+		c.wg.Wait()
+		return c.val, c.err, true
+	}
+	c := &call{}
+	c.wg.Add(1)
+	g.m[key] = c
+	g.mu.Unlock()
+
+	c.val, c.err = fn()
+
+	g.mu.Lock()
+	delete(g.m, key)
+	shared = c.dups > 0
+	g.mu.Unlock()
+	c.wg.Done()
+
+	return c.val, c.err, shared
+}
+
+// DoContext is like Do, but fn runs with its own context, which holds the values of the first caller's context and
+// reports the latest deadline of the callers still waiting. A caller whose context is done stops waiting and gets
+// its context's error, without failing the call for the other callers. The call's context is canceled when the
+// last caller stops waiting, with that caller's context error, so it is done with context.DeadlineExceeded once
+// the latest deadline passed; the next call with the same key starts a new execution.
+func (g *Group) DoContext(ctx context.Context, key string, fn func(context.Context) (interface{}, error)) (v interface{}, err error, shared bool) {
+	g.mu.Lock()
+	if g.mc == nil {
+		g.mc = make(map[string]*contextCall)
+	}
+	c, ok := g.mc[key]
+	if ok {
+		c.dups++
+		c.join(ctx)
+	} else {
+		c = &contextCall{done: make(chan struct{}), ctxDone: make(chan struct{})}
+		c.join(ctx)
+		g.mc[key] = c
+		go func() {
+			c.val, c.err = fn(detached{parent: ctx, g: g, c: c})
+			g.mu.Lock()
+			if g.mc[key] == c {
+				delete(g.mc, key)
+			}
+			c.cancel(context.Canceled)
+			g.mu.Unlock()
+			close(c.done)
+		}()
+	}
+	g.mu.Unlock()
+
+	select {
+	case <-c.done:
+		g.mu.Lock()
+		shared = c.dups > 0
+		g.mu.Unlock()
+		return c.val, c.err, shared
+	case <-ctx.Done():
+		g.mu.Lock()
+		c.leave(ctx)
+		if c.refs == 0 {
+			c.cancel(ctx.Err())
+			if g.mc[key] == c {
+				delete(g.mc, key)
+			}
+		}
+		shared = c.dups > 0
+		g.mu.Unlock()
+		return nil, ctx.Err(), shared
+	}
+}
+
+// detached is the context of a call: it has the values of its parent, but is canceled only when all the callers
+// stopped waiting. Its deadline is the latest one of the callers still waiting for the call, or none if any of them
+// has none, so that fn can give up early on work none of them would wait for; unlike the deadlines of other
+// contexts, it may change while the call runs.
+type detached struct {
+	parent context.Context
+	g      *Group
+	c      *contextCall
+}
+
+func (d detached) Deadline() (deadline time.Time, ok bool) {
+	d.g.mu.Lock()
+	defer d.g.mu.Unlock()
+
+	if d.c.unbounded > 0 || len(d.c.deadlines) == 0 {
+		return time.Time{}, false
+	}
+	for _, t := range d.c.deadlines {
+		if t.After(deadline) {
+			deadline = t
+		}
+	}
+	return deadline, true
+}
+
+func (d detached) Done() <-chan struct{} {
+	return d.c.ctxDone
+}
+
+func (d detached) Err() error {
+	d.g.mu.Lock()
+	defer d.g.mu.Unlock()
+
+	return d.c.ctxErr
+}
+
+func (d detached) Value(key interface{}) interface{} {
+	return d.parent.Value(key)
+}
@@ -0,0 +1,201 @@
+package singleflight
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGroup_Do(t *testing.T) {
+	var g Group
+	v, err, _ := g.Do("key", func() (interface{}, error) {
+		return "bar", nil
+	})
+	if v.(string) != "bar" || err != nil {
+		t.Fatalf("Do = %v, %v", v, err)
+	}
+
+	someErr := errors.New("some error")
+	if _, err, _ = g.Do("key", func() (interface{}, error) { return nil, someErr }); err != someErr {
+		t.Fatalf("expected error %v, got %v", someErr, err)
+	}
+}
+
+func TestGroup_DoDuplicates(t *testing.T) {
+	var (
+		g       Group
+		calls   int32
+		wg      sync.WaitGroup
+		release = make(chan struct{})
+	)
+	fn := func() (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return 42, nil
+	}
+	const n = 10
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if v, err, _ := g.Do("key", fn); v.(int) != 42 || err != nil {
+				t.Errorf("Do = %v, %v", v, err)
+			}
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Fatalf("expected 1 call, got %d", got)
+	}
+}
+
+func TestGroup_DoContextCancel(t *testing.T) {
+	var (
+		g       Group
+		calls   int32
+		release = make(chan struct{})
+	)
+	fn := func(ctx context.Context) (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		select {
+		case <-release:
+			return 42, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	canceled, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() {
+		_, err, _ := g.DoContext(canceled, "key", fn)
+		errc <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	vc := make(chan interface{}, 1)
+	go func() {
+		v, err, shared := g.DoContext(context.Background(), "key", fn)
+		if err != nil || !shared {
+			t.Errorf("DoContext = %v, %v, %t", v, err, shared)
+		}
+		vc <- v
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	cancel()
+	if err := <-errc; err != context.Canceled {
+		t.Fatalf("expected canceled caller to get %v, got %v", context.Canceled, err)
+	}
+	close(release)
+	if v := <-vc; v != 42 {
+		t.Fatalf("expected remaining caller to get 42, got %v", v)
+	}
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Fatalf("expected 1 call, got %d", got)
+	}
+}
+
+func TestGroup_DoContextAbandoned(t *testing.T) {
+	var g Group
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		_, _, _ = g.DoContext(ctx, "key", func(ctx context.Context) (interface{}, error) {
+			<-ctx.Done()
+			close(stopped)
+			return nil, ctx.Err()
+		})
+	}()
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("expected the call to be canceled once all callers left")
+	}
+
+	v, err, _ := g.DoContext(context.Background(), "key", func(context.Context) (interface{}, error) {
+		return "fresh", nil
+	})
+	if v != "fresh" || err != nil {
+		t.Fatalf("expected a new execution after the abandoned one, got %v, %v", v, err)
+	}
+}
+
+func TestGroup_DoContextDeadline(t *testing.T) {
+	var g Group
+	deadlines := make(chan time.Time)
+	joined := make(chan struct{})
+	fn := func(ctx context.Context) (interface{}, error) {
+		d, _ := ctx.Deadline()
+		deadlines <- d
+		<-joined
+		d, ok := ctx.Deadline()
+		if !ok {
+			return nil, errors.New("expected a deadline")
+		}
+		deadlines <- d
+		return nil, nil
+	}
+
+	short, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	long, cancelLong := context.WithTimeout(context.Background(), time.Hour)
+	defer cancelLong()
+
+	done := make(chan struct{})
+	go func() {
+		_, _, _ = g.DoContext(short, "key", fn)
+		close(done)
+	}()
+	first := <-deadlines
+	if expected, _ := short.Deadline(); !first.Equal(expected) {
+		t.Fatalf("expected the deadline of the only caller, %s, got %s", expected, first)
+	}
+
+	go func() { _, _, _ = g.DoContext(long, "key", fn) }()
+	for {
+		// wait for the second caller to join
+		g.mu.Lock()
+		refs := g.mc["key"].refs
+		g.mu.Unlock()
+		if refs == 2 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	close(joined)
+	if expected, _ := long.Deadline(); !(<-deadlines).Equal(expected) {
+		t.Fatal("expected the latest deadline of the callers")
+	}
+	<-done
+}
+
+func TestGroup_DoContextDeadlineExceeded(t *testing.T) {
+	var g Group
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	errs := make(chan error, 1)
+	_, err, _ := g.DoContext(ctx, "key", func(ctx context.Context) (interface{}, error) {
+		<-ctx.Done()
+		errs <- ctx.Err()
+		return nil, ctx.Err()
+	})
+	if err != context.DeadlineExceeded {
+		t.Fatalf("expected the caller to get %v, got %v", context.DeadlineExceeded, err)
+	}
+	select {
+	case err = <-errs:
+		if err != context.DeadlineExceeded {
+			t.Fatalf("expected the call's context to be done with %v, got %v", context.DeadlineExceeded, err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the call's context to be done after the deadline")
+	}
+}
@@ -1,11 +1,13 @@
 package util
 
 import (
+	"context"
 	"encoding/xml"
 	"errors"
 	"io"
 	"io/ioutil"
 	"strings"
+	"sync"
 
 	"github.com/gabriel-vasile/mimetype"
 	tjson "github.com/tmaxmax/json"
@@ -50,3 +52,50 @@ func ReadAll(r io.ReadCloser) ([]byte, error) {
 func mimeType(data []byte) string {
 	return strings.Split(mimetype.Detect(data).String(), ";")[0]
 }
+
+// Parallel calls fn for every index in [0, n), running at most limit calls at a time. The context passed to fn is
+// canceled as soon as a call fails; the first error is returned after all the started calls have finished.
+// If the context is canceled before all the calls are started, its error is returned; if all the calls succeeded,
+// nil is returned, even if the context was canceled afterwards.
+func Parallel(ctx context.Context, n, limit int, fn func(ctx context.Context, i int) error) error {
+	if limit <= 0 {
+		limit = 1
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+		sem      = make(chan struct{}, limit)
+		started  int
+	)
+loop:
+	for ; started < n; started++ {
+		i := started
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			break loop
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			if err := fn(ctx, i); err != nil {
+				once.Do(func() {
+					firstErr = err
+					cancel()
+				})
+			}
+		}(i)
+	}
+	wg.Wait()
+	if firstErr == nil && started < n {
+		firstErr = ctx.Err()
+	}
+	return firstErr
+}
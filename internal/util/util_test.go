@@ -0,0 +1,59 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+)
+
+func TestParallel(t *testing.T) {
+	var calls int32
+	err := Parallel(context.Background(), 10, 3, func(ctx context.Context, i int) error {
+		atomic.AddInt32(&calls, 1)
+		return nil
+	})
+	if err != nil || calls != 10 {
+		t.Fatalf("expected 10 successful calls, got %d calls and error %v", calls, err)
+	}
+
+	someErr := errors.New("some error")
+	err = Parallel(context.Background(), 10, 1, func(ctx context.Context, i int) error {
+		if i == 2 {
+			return someErr
+		}
+		return nil
+	})
+	if err != someErr {
+		t.Fatalf("expected %v, got %v", someErr, err)
+	}
+}
+
+func TestParallel_CanceledAfterSuccess(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var done int32
+	err := Parallel(ctx, 2, 2, func(context.Context, int) error {
+		// the last call to finish cancels the parent context
+		if atomic.AddInt32(&done, 1) == 2 {
+			cancel()
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("expected no error when all calls succeeded, got %v", err)
+	}
+}
+
+func TestParallel_CanceledBeforeStart(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var calls int32
+	err := Parallel(ctx, 100, 1, func(context.Context, int) error {
+		atomic.AddInt32(&calls, 1)
+		return nil
+	})
+	if err != context.Canceled {
+		t.Fatalf("expected %v, got %v after %d calls", context.Canceled, err, calls)
+	}
+}
@@ -0,0 +1,37 @@
+package lufthansa
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	offersAPI = fetchAPI + "/offers"
+
+	dateLayout          = "2006-01-02"
+	localDateTimeLayout = "2006-01-02T15:04"
+)
+
+// CabinClass is the cabin class code used by the Offers API endpoints.
+type CabinClass string
+
+// The cabin classes recognized by the Lufthansa Offers API.
+const (
+	CabinFirst          CabinClass = "F"
+	CabinBusiness       CabinClass = "C"
+	CabinPremiumEconomy CabinClass = "E"
+	CabinEconomy        CabinClass = "M"
+)
+
+// parseLocalDateTime parses the date-time format the Lufthansa API uses for local times (no seconds, no offset).
+// Empty strings result in the zero time.
+func parseLocalDateTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(localDateTimeLayout, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("lufthansa: invalid local date-time %q: %w", s, err)
+	}
+	return t, nil
+}
@@ -0,0 +1,375 @@
+package lufthansa
+
+import (
+	"context"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tmaxmax/lufthansaapi/internal/util"
+	"golang.org/x/text/language"
+)
+
+// TierCode is the frequent flyer status code used by the lounges endpoint.
+type TierCode string
+
+// The frequent flyer tiers recognized by the Lufthansa Offers API.
+const (
+	TierHON                TierCode = "HON"
+	TierSenator            TierCode = "SEN"
+	TierFrequentTraveller  TierCode = "FTL"
+	TierStarAllianceGold   TierCode = "SGC"
+	TierStarAllianceSilver TierCode = "SSC"
+)
+
+// LoungeFeature is a set of lounge amenities. Combine them with bitwise or and check them with LoungeFeature.Has.
+type LoungeFeature uint8
+
+// The lounge amenities sent by the lounges endpoint.
+const (
+	LoungeShowers LoungeFeature = 1 << iota
+	LoungeRelaxingRooms
+	LoungeWiFi
+	LoungeWorkstations
+	LoungeMeetingRooms
+	LoungeSmokingArea
+)
+
+// Has reports whether all the features in o are present in f.
+func (f LoungeFeature) Has(o LoungeFeature) bool {
+	return f&o == o
+}
+
+func (f LoungeFeature) String() string {
+	names := [...]string{"showers", "relaxing rooms", "Wi-Fi", "workstations", "meeting rooms", "smoking area"}
+	var parts []string
+	for i := range names {
+		if f.Has(1 << i) {
+			parts = append(parts, names[i])
+		}
+	}
+	if len(parts) == 0 {
+		return "(none)"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// OpeningPeriod is an interval of a weekday in which a lounge is open. Open and Close are offsets from midnight;
+// a Close greater than 24 hours means the lounge closes on the following day.
+type OpeningPeriod struct {
+	Weekday time.Weekday
+	Open    time.Duration
+	Close   time.Duration
+}
+
+// OpeningHours holds the opening hours of a lounge, as parsed from the (english, if available) text sent by the API.
+// Text is always set, while Periods is empty if the text couldn't be interpreted.
+type OpeningHours struct {
+	Periods []OpeningPeriod
+	Text    string
+}
+
+var (
+	weekdayPrefixes = [...]string{"su", "mo", "tu", "we", "th", "fr", "sa"}
+
+	openingDaysRegexp  = regexp.MustCompile(`(?i)^\s*(daily|[a-z]{2,9})\.?(?:\s*-\s*([a-z]{2,9})\.?)?:?\s*`)
+	openingRangeRegexp = regexp.MustCompile(`(\d{1,2})[:.](\d{2})\s*(?:-|–|to)\s*(\d{1,2})[:.](\d{2})`)
+	open24Regexp       = regexp.MustCompile(`(?i)24\s*h`)
+)
+
+func parseWeekday(s string) (time.Weekday, bool) {
+	s = strings.ToLower(s)
+	for i, p := range weekdayPrefixes {
+		if strings.HasPrefix(s, p) {
+			return time.Weekday(i), true
+		}
+	}
+	return 0, false
+}
+
+// parseOpeningDays parses the weekday specification at the start of s. It returns the days and the rest of the
+// string, or all the days of the week if s doesn't start with a weekday specification.
+func parseOpeningDays(s string) ([]time.Weekday, string) {
+	all := []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Saturday, time.Sunday}
+
+	m := openingDaysRegexp.FindStringSubmatch(s)
+	if m == nil || strings.EqualFold(m[1], "daily") {
+		if m != nil {
+			s = s[len(m[0]):]
+		}
+		return all, s
+	}
+	first, ok := parseWeekday(m[1])
+	if !ok {
+		return all, s
+	}
+	last := first
+	if m[2] != "" {
+		if last, ok = parseWeekday(m[2]); !ok {
+			return all, s
+		}
+	}
+	var days []time.Weekday
+	for d := first; ; d = (d + 1) % 7 {
+		days = append(days, d)
+		if d == last {
+			break
+		}
+	}
+	return days, s[len(m[0]):]
+}
+
+func clock(h, m string) time.Duration {
+	hours, _ := strconv.Atoi(h)
+	minutes, _ := strconv.Atoi(m)
+	return time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute
+}
+
+// parseOpeningHours interprets texts like "Mon-Fri 05:30 - 22:00, Sat 06:00 - 20:00" or "daily 24h".
+func parseOpeningHours(text string) OpeningHours {
+	oh := OpeningHours{Text: text}
+	for _, segment := range strings.FieldsFunc(text, func(r rune) bool {
+		return r == ',' || r == ';' || r == '\n'
+	}) {
+		days, rest := parseOpeningDays(segment)
+		var ranges [][2]time.Duration
+		if open24Regexp.MatchString(rest) {
+			ranges = append(ranges, [2]time.Duration{0, 24 * time.Hour})
+		}
+		for _, m := range openingRangeRegexp.FindAllStringSubmatch(rest, -1) {
+			open, end := clock(m[1], m[2]), clock(m[3], m[4])
+			if end <= open {
+				end += 24 * time.Hour
+			}
+			ranges = append(ranges, [2]time.Duration{open, end})
+		}
+		for _, d := range days {
+			for _, r := range ranges {
+				oh.Periods = append(oh.Periods, OpeningPeriod{Weekday: d, Open: r[0], Close: r[1]})
+			}
+		}
+	}
+	return oh
+}
+
+// OpenAt reports whether the lounge is open at the given time. The wall clock of t is used, so pass it in
+// the lounge's local time zone. If the opening hours couldn't be parsed, OpenAt always returns false.
+func (oh *OpeningHours) OpenAt(t time.Time) bool {
+	offset := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+	for _, p := range oh.Periods {
+		if p.Weekday == t.Weekday() && offset >= p.Open && offset < p.Close {
+			return true
+		}
+		// periods that end after midnight continue on the next day
+		if (p.Weekday+1)%7 == t.Weekday() && offset+24*time.Hour < p.Close {
+			return true
+		}
+	}
+	return false
+}
+
+type (
+	loungeNamesUnmarshal struct {
+		Names []referenceNameUnmarshal `xml:"Names>Name" json:"Names.Name"`
+	}
+	loungeFeaturesUnmarshal struct {
+		ShowerFacilities bool `xml:"ShowerFacilities" json:"ShowerFacilities"`
+		RelaxingRooms    bool `xml:"RelaxingRooms" json:"RelaxingRooms"`
+		WLANFacility     bool `xml:"WLANFacility" json:"WLANFacility"`
+		WorkingStations  bool `xml:"WorkingStations" json:"WorkingStations"`
+		MeetingRooms     bool `xml:"MeetingRooms" json:"MeetingRooms"`
+		SmokingArea      bool `xml:"SmokingArea" json:"SmokingArea"`
+	}
+	loungeUnmarshal struct {
+		Names        []referenceNameUnmarshal `xml:"Names>Name" json:"Names.Name"`
+		AirportCode  string                   `xml:"AirportCode" json:"AirportCode"`
+		CityCode     string                   `xml:"CityCode" json:"CityCode"`
+		Locations    []loungeNamesUnmarshal   `xml:"Locations>Location" json:"Locations.Location"`
+		OpeningHours []loungeNamesUnmarshal   `xml:"OpeningHours>OpeningHours" json:"OpeningHours.OpeningHours"`
+		Features     loungeFeaturesUnmarshal  `xml:"Features" json:"Features"`
+	}
+	loungesUnmarshal struct {
+		Lounges []loungeUnmarshal `xml:"Lounges>Lounge" json:"LoungeResource.Lounges.Lounge"`
+	}
+
+	// Lounge holds the data of a single lounge. Locations contains the location descriptions
+	// (terminal, gate area etc.) in all the languages requested.
+	Lounge struct {
+		Names        referenceNames
+		AirportCode  string
+		CityCode     string
+		Locations    []referenceNames
+		OpeningHours []OpeningHours
+		Features     LoungeFeature
+	}
+	// Lounges represents the decoded API response of the lounges endpoint.
+	// Lufthansa API documentation: https://developer.lufthansa.com/docs/read/api_details/offers/Lounges
+	Lounges struct {
+		Lounges []Lounge
+	}
+)
+
+// englishOrAny returns the english name or, if there isn't an english one, the name whose language tag is the
+// smallest, so that the same name is picked every time.
+func (rn referenceNames) englishOrAny() string {
+	if n, ok := rn[language.English]; ok {
+		return n
+	}
+	var (
+		first string
+		name  string
+	)
+	for tag, n := range rn {
+		if t := tag.String(); first == "" || t < first {
+			first, name = t, n
+		}
+	}
+	return name
+}
+
+func (f *LoungeFeature) make(fu *loungeFeaturesUnmarshal) {
+	flags := [...]bool{fu.ShowerFacilities, fu.RelaxingRooms, fu.WLANFacility, fu.WorkingStations, fu.MeetingRooms, fu.SmokingArea}
+	*f = 0
+	for i, set := range flags {
+		if set {
+			*f |= 1 << i
+		}
+	}
+}
+
+func (l *Lounge) make(lu *loungeUnmarshal) {
+	l.Names.make(lu.Names)
+	l.AirportCode = lu.AirportCode
+	l.CityCode = lu.CityCode
+	l.Locations = make([]referenceNames, len(lu.Locations))
+	for i := range lu.Locations {
+		l.Locations[i].make(lu.Locations[i].Names)
+	}
+	l.OpeningHours = make([]OpeningHours, len(lu.OpeningHours))
+	for i := range lu.OpeningHours {
+		var rn referenceNames
+		rn.make(lu.OpeningHours[i].Names)
+		l.OpeningHours[i] = parseOpeningHours(rn.englishOrAny())
+	}
+	l.Features.make(&lu.Features)
+}
+
+// OpenAt reports whether any of the lounge's opening hours include the given time.
+// See OpeningHours.OpenAt for how t is interpreted.
+func (l *Lounge) OpenAt(t time.Time) bool {
+	for i := range l.OpeningHours {
+		if l.OpeningHours[i].OpenAt(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// HasFeatures reports whether the lounge offers all the given features.
+func (l *Lounge) HasFeatures(f LoungeFeature) bool {
+	return l.Features.Has(f)
+}
+
+func (l *Lounge) String() string {
+	return util.Stringer.Stringify(l, "")
+}
+
+func (ls *Lounges) decode(r io.ReadCloser) error {
+	lu := &loungesUnmarshal{}
+	if err := util.Decode(r, lu); err != nil {
+		return err
+	}
+	ls.Lounges = make([]Lounge, len(lu.Lounges))
+	for i := range ls.Lounges {
+		ls.Lounges[i].make(&lu.Lounges[i])
+	}
+	return nil
+}
+
+func (ls *Lounges) String() string {
+	return util.Stringer.Stringify(ls, "")
+}
+
+// LoungeFilter is a predicate used by Lounges.Filter.
+type LoungeFilter func(*Lounge) bool
+
+// LoungeOpenAt returns a filter that keeps the lounges open at the given time.
+func LoungeOpenAt(t time.Time) LoungeFilter {
+	return func(l *Lounge) bool {
+		return l.OpenAt(t)
+	}
+}
+
+// LoungeHasFeatures returns a filter that keeps the lounges offering all the given features.
+func LoungeHasFeatures(f LoungeFeature) LoungeFilter {
+	return func(l *Lounge) bool {
+		return l.HasFeatures(f)
+	}
+}
+
+// Filter returns the lounges that satisfy all the given filters. The receiver is not modified.
+func (ls *Lounges) Filter(filters ...LoungeFilter) []Lounge {
+	var r []Lounge
+outer:
+	for i := range ls.Lounges {
+		for _, f := range filters {
+			if !f(&ls.Lounges[i]) {
+				continue outer
+			}
+		}
+		r = append(r, ls.Lounges[i])
+	}
+	return r
+}
+
+// LoungeParams is a struct containing the optional parameters of the lounges endpoint.
+//
+// Fields:
+//   - CabinClass restricts the lounges to the ones accessible from the given cabin class.
+//   - TierCode restricts the lounges to the ones accessible with the given frequent flyer status.
+//   - Lang is a language.Tag pointer. If it's nil, the API sends the names in all available languages.
+type LoungeParams struct {
+	CabinClass CabinClass
+	TierCode   TierCode
+	Lang       *language.Tag
+}
+
+// ToURL transforms a LoungeParams struct into an URL usable format,
+// so that it can be concatenated to the lounges endpoint URL.
+func (p *LoungeParams) ToURL() string {
+	if p == nil {
+		return ""
+	}
+	mustWriteAmp := false
+	var sb strings.Builder
+	if p.CabinClass != "" {
+		writeAmp(&sb, &mustWriteAmp)
+		sb.WriteString("cabinClass=")
+		sb.WriteString(string(p.CabinClass))
+	}
+	if p.TierCode != "" {
+		writeAmp(&sb, &mustWriteAmp)
+		sb.WriteString("tierCode=")
+		sb.WriteString(string(p.TierCode))
+	}
+	if p.Lang != nil {
+		writeAmp(&sb, &mustWriteAmp)
+		sb.WriteString("lang=")
+		sb.WriteString(langCode(*p.Lang))
+	}
+	return sb.String()
+}
+
+// FetchLounges requests the lounges at the given location, which is either an airport or a city code.
+// Lufthansa API documentation: https://developer.lufthansa.com/docs/read/api_details/offers/Lounges
+func (a *API) FetchLounges(ctx context.Context, location string, p *LoungeParams) (*Lounges, error) {
+	fetched, err := a.fetch(ctx, offersAPI+"/lounges/"+location+p.ToURL())
+	if err != nil {
+		return nil, err
+	}
+
+	ls := &Lounges{}
+	return ls, ls.decode(fetched)
+}
@@ -0,0 +1,66 @@
+package lufthansa_test
+
+import (
+	"testing"
+	"time"
+
+	lufthansa "github.com/tmaxmax/lufthansaapi"
+	"golang.org/x/text/language"
+)
+
+func TestAPI_FetchLounges(t *testing.T) {
+	ls, err := api.FetchLounges(ctx, "FRA", &lufthansa.LoungeParams{
+		CabinClass: lufthansa.CabinBusiness,
+		Lang:       &language.English,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("%s", ls)
+
+	open := ls.Filter(lufthansa.LoungeOpenAt(time.Date(2020, time.August, 26, 12, 0, 0, 0, time.UTC)), lufthansa.LoungeHasFeatures(lufthansa.LoungeShowers))
+	t.Logf("%d lounges with showers open at noon", len(open))
+}
+
+func TestLounges_Filter(t *testing.T) {
+	ls := &lufthansa.Lounges{Lounges: []lufthansa.Lounge{
+		{
+			AirportCode: "FRA",
+			Features:    lufthansa.LoungeShowers | lufthansa.LoungeWiFi,
+			OpeningHours: []lufthansa.OpeningHours{{Periods: []lufthansa.OpeningPeriod{
+				{Weekday: time.Wednesday, Open: 5 * time.Hour, Close: 26 * time.Hour},
+			}}},
+		},
+		{
+			AirportCode: "MUC",
+			Features:    lufthansa.LoungeWiFi,
+			OpeningHours: []lufthansa.OpeningHours{{Periods: []lufthansa.OpeningPeriod{
+				{Weekday: time.Wednesday, Open: 6 * time.Hour, Close: 22 * time.Hour},
+			}}},
+		},
+	}}
+	wednesdayNoon := time.Date(2020, time.August, 26, 12, 0, 0, 0, time.UTC)
+	thursdayOneAM := wednesdayNoon.Add(13 * time.Hour)
+
+	tests := []struct {
+		filters  []lufthansa.LoungeFilter
+		expected []string
+	}{
+		{nil, []string{"FRA", "MUC"}},
+		{[]lufthansa.LoungeFilter{lufthansa.LoungeOpenAt(wednesdayNoon)}, []string{"FRA", "MUC"}},
+		{[]lufthansa.LoungeFilter{lufthansa.LoungeOpenAt(thursdayOneAM)}, []string{"FRA"}},
+		{[]lufthansa.LoungeFilter{lufthansa.LoungeHasFeatures(lufthansa.LoungeWiFi | lufthansa.LoungeShowers)}, []string{"FRA"}},
+		{[]lufthansa.LoungeFilter{lufthansa.LoungeHasFeatures(lufthansa.LoungeMeetingRooms)}, nil},
+	}
+	for i, test := range tests {
+		got := ls.Filter(test.filters...)
+		if len(got) != len(test.expected) {
+			t.Fatalf("test %d: expected %d lounges, got %d", i, len(test.expected), len(got))
+		}
+		for j := range got {
+			if got[j].AirportCode != test.expected[j] {
+				t.Fatalf("test %d: expected %s, got %s", i, test.expected[j], got[j].AirportCode)
+			}
+		}
+	}
+}
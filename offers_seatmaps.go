@@ -0,0 +1,345 @@
+package lufthansa
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/tmaxmax/lufthansaapi/internal/util"
+)
+
+// SeatCharacteristic is a set of seat properties, as sent by the seat maps endpoint. Combine them with bitwise or
+// and check them with SeatCharacteristic.Has.
+type SeatCharacteristic uint16
+
+// The seat characteristics this package recognizes. Codes not listed here are kept in Seat.Codes.
+const (
+	SeatWindow SeatCharacteristic = 1 << iota
+	SeatAisle
+	SeatCenter
+	SeatExitRow
+	SeatBassinet
+	SeatBlocked
+	SeatNoSeat
+)
+
+var seatCharacteristicCodes = map[string]SeatCharacteristic{
+	"W": SeatWindow,
+	"A": SeatAisle,
+	"9": SeatCenter,
+	"E": SeatExitRow,
+	"B": SeatBassinet,
+	"1": SeatBlocked,
+	"8": SeatNoSeat,
+}
+
+// Has reports whether all the characteristics in o are present in s.
+func (s SeatCharacteristic) Has(o SeatCharacteristic) bool {
+	return s&o == o
+}
+
+func (s SeatCharacteristic) String() string {
+	names := [...]string{"window", "aisle", "center", "exit row", "bassinet", "blocked", "no seat"}
+	var parts []string
+	for i := range names {
+		if s.Has(1 << i) {
+			parts = append(parts, names[i])
+		}
+	}
+	if len(parts) == 0 {
+		return "(none)"
+	}
+	return strings.Join(parts, ", ")
+}
+
+type (
+	seatCharacteristicUnmarshal struct {
+		Code string `xml:"Code,attr" json:"@Code"`
+	}
+	seatRowsUnmarshal struct {
+		First int `xml:"First" json:"First"`
+		Last  int `xml:"Last" json:"Last"`
+	}
+	seatColumnUnmarshal struct {
+		Position string `xml:"Position,attr" json:"@Position"`
+		Type     string `xml:",chardata" json:"$"`
+	}
+	seatDisplayUnmarshal struct {
+		Columns []seatColumnUnmarshal `xml:"Columns" json:"Columns"`
+		Rows    seatRowsUnmarshal     `xml:"Rows" json:"Rows"`
+	}
+	seatDetailUnmarshal struct {
+		Column             string                        `xml:"Location>Column" json:"Location.Column"`
+		Row                int                           `xml:"Location>Row>Number" json:"Location.Row.Number"`
+		RowCharacteristics []seatCharacteristicUnmarshal `xml:"Location>Row>Characteristics>Characteristic" json:"Location.Row.Characteristics.Characteristic"`
+		Characteristics    []seatCharacteristicUnmarshal `xml:"Characteristics>Characteristic" json:"Characteristics.Characteristic"`
+	}
+	seatMapFlightUnmarshal struct {
+		Origin       string `xml:"Departure>AirportCode" json:"Departure.AirportCode"`
+		Departure    string `xml:"Departure>ScheduledTimeLocal>DateTime" json:"Departure.ScheduledTimeLocal.DateTime"`
+		Destination  string `xml:"Arrival>AirportCode" json:"Arrival.AirportCode"`
+		Arrival      string `xml:"Arrival>ScheduledTimeLocal>DateTime" json:"Arrival.ScheduledTimeLocal.DateTime"`
+		AirlineID    string `xml:"MarketingCarrier>AirlineID" json:"MarketingCarrier.AirlineID"`
+		FlightNumber string `xml:"MarketingCarrier>FlightNumber" json:"MarketingCarrier.FlightNumber"`
+		AircraftCode string `xml:"Equipment>AircraftCode" json:"Equipment.AircraftCode"`
+	}
+	seatMapUnmarshal struct {
+		Flight       seatMapFlightUnmarshal `xml:"Flights>Flight" json:"SeatAvailabilityResource.Flights.Flight"`
+		WingRows     seatRowsUnmarshal      `xml:"CabinLayout>WingPosition>Rows" json:"SeatAvailabilityResource.CabinLayout.WingPosition.Rows"`
+		ExitRows     seatRowsUnmarshal      `xml:"CabinLayout>ExitRowPosition>Rows" json:"SeatAvailabilityResource.CabinLayout.ExitRowPosition.Rows"`
+		SeatDisplays []seatDisplayUnmarshal `xml:"SeatDisplay" json:"SeatAvailabilityResource.SeatDisplay"`
+		SeatDetails  []seatDetailUnmarshal  `xml:"SeatDetails" json:"SeatAvailabilityResource.SeatDetails"`
+	}
+
+	// SeatMapFlight holds the flight the seat map was requested for.
+	SeatMapFlight struct {
+		Origin       string
+		Destination  string
+		Departure    time.Time
+		Arrival      time.Time
+		AirlineID    string
+		FlightNumber string
+		AircraftCode string
+	}
+	// SeatColumn is a column of a deck. Aisle is true for the columns that are next to an aisle.
+	SeatColumn struct {
+		Letter string
+		Window bool
+		Aisle  bool
+	}
+	// Seat is a single seat of a row. Codes holds the raw characteristic codes, including the ones that aren't
+	// represented in Characteristics.
+	Seat struct {
+		Column          string
+		Characteristics SeatCharacteristic
+		Codes           []string
+	}
+	// SeatRow is a row of a deck. Seats has the same length as the deck's columns, a nil entry meaning that there
+	// is no seat at that position.
+	SeatRow struct {
+		Number  int
+		ExitRow bool
+		Wing    bool
+		Seats   []*Seat
+	}
+	// Deck is a single seat display of the cabin: a block of rows sharing the same columns layout.
+	Deck struct {
+		Columns []SeatColumn
+		Rows    []SeatRow
+	}
+	// SeatMap represents the decoded API response of the seat maps endpoint.
+	// Lufthansa API documentation: https://developer.lufthansa.com/docs/read/api_details/offers/Seat_Maps
+	SeatMap struct {
+		Flight SeatMapFlight
+		Cabin  CabinClass
+		Decks  []Deck
+	}
+)
+
+func (s *Seat) make(su *seatDetailUnmarshal) {
+	s.Column = su.Column
+	s.Codes = make([]string, 0, len(su.Characteristics))
+	for _, c := range su.Characteristics {
+		s.Codes = append(s.Codes, c.Code)
+		s.Characteristics |= seatCharacteristicCodes[c.Code]
+	}
+}
+
+func (s *Seat) String() string {
+	return util.Stringer.Stringify(s, "")
+}
+
+func (f *SeatMapFlight) make(fu *seatMapFlightUnmarshal) (err error) {
+	f.Origin = fu.Origin
+	f.Destination = fu.Destination
+	f.AirlineID = fu.AirlineID
+	f.FlightNumber = fu.FlightNumber
+	f.AircraftCode = fu.AircraftCode
+	if f.Departure, err = parseLocalDateTime(fu.Departure); err != nil {
+		return err
+	}
+	f.Arrival, err = parseLocalDateTime(fu.Arrival)
+	return err
+}
+
+func (d *Deck) make(du *seatDisplayUnmarshal, wing, exit seatRowsUnmarshal) {
+	d.Columns = make([]SeatColumn, len(du.Columns))
+	for i, c := range du.Columns {
+		d.Columns[i] = SeatColumn{
+			Letter: c.Position,
+			Window: c.Type == "W",
+			Aisle:  c.Type == "A",
+		}
+	}
+	if du.Rows.Last < du.Rows.First {
+		return
+	}
+	d.Rows = make([]SeatRow, 0, du.Rows.Last-du.Rows.First+1)
+	for n := du.Rows.First; n <= du.Rows.Last; n++ {
+		d.Rows = append(d.Rows, SeatRow{
+			Number:  n,
+			ExitRow: n >= exit.First && n <= exit.Last,
+			Wing:    n >= wing.First && n <= wing.Last,
+			Seats:   make([]*Seat, len(d.Columns)),
+		})
+	}
+}
+
+// row returns the row with the given number, or nil if the deck doesn't contain it.
+func (d *Deck) row(number int) *SeatRow {
+	if len(d.Rows) == 0 || number < d.Rows[0].Number || number > d.Rows[len(d.Rows)-1].Number {
+		return nil
+	}
+	return &d.Rows[number-d.Rows[0].Number]
+}
+
+func (d *Deck) column(letter string) int {
+	for i := range d.Columns {
+		if d.Columns[i].Letter == letter {
+			return i
+		}
+	}
+	return -1
+}
+
+// AisleAfter reports whether there is an aisle between the column at index i and the next one.
+func (d *Deck) AisleAfter(i int) bool {
+	return i >= 0 && i+1 < len(d.Columns) && d.Columns[i].Aisle && d.Columns[i+1].Aisle
+}
+
+// Seat returns the seat at the given row and column, or nil if there is no such seat.
+func (d *Deck) Seat(row int, column string) *Seat {
+	r, c := d.row(row), d.column(column)
+	if r == nil || c == -1 {
+		return nil
+	}
+	return r.Seats[c]
+}
+
+// Symbols used by Deck.Grid to draw each seat.
+const (
+	GridSeat     = 'o'
+	GridExitRow  = 'E'
+	GridBassinet = 'B'
+	GridBlocked  = 'X'
+	GridNoSeat   = ' '
+)
+
+func gridSymbol(s *Seat) byte {
+	switch {
+	case s == nil, s.Characteristics.Has(SeatNoSeat):
+		return GridNoSeat
+	case s.Characteristics.Has(SeatBlocked):
+		return GridBlocked
+	case s.Characteristics.Has(SeatBassinet):
+		return GridBassinet
+	case s.Characteristics.Has(SeatExitRow):
+		return GridExitRow
+	}
+	return GridSeat
+}
+
+// Grid renders the deck as an ASCII grid: a header line with the column letters, followed by a line for each row,
+// prefixed with the row number. Aisles are drawn as a blank column, and each seat uses one of the Grid symbols.
+func (d *Deck) Grid() string {
+	width := len(fmt.Sprint(d.lastRowNumber()))
+	var sb strings.Builder
+
+	sb.WriteString(strings.Repeat(" ", width+1))
+	for i := range d.Columns {
+		sb.WriteString(d.Columns[i].Letter)
+		if d.AisleAfter(i) {
+			sb.WriteByte(' ')
+		}
+	}
+	for _, r := range d.Rows {
+		sb.WriteByte('\n')
+		sb.WriteString(fmt.Sprintf("%*d ", width, r.Number))
+		for i, s := range r.Seats {
+			sb.WriteByte(gridSymbol(s))
+			if d.AisleAfter(i) {
+				sb.WriteByte(' ')
+			}
+		}
+	}
+	return sb.String()
+}
+
+func (d *Deck) lastRowNumber() int {
+	if len(d.Rows) == 0 {
+		return 0
+	}
+	return d.Rows[len(d.Rows)-1].Number
+}
+
+func (d *Deck) String() string {
+	return d.Grid()
+}
+
+func (sm *SeatMap) decode(r io.ReadCloser) error {
+	su := &seatMapUnmarshal{}
+	if err := util.Decode(r, su); err != nil {
+		return err
+	}
+	if err := sm.Flight.make(&su.Flight); err != nil {
+		return err
+	}
+	sm.Decks = make([]Deck, len(su.SeatDisplays))
+	for i := range su.SeatDisplays {
+		sm.Decks[i].make(&su.SeatDisplays[i], su.WingRows, su.ExitRows)
+	}
+	for i := range su.SeatDetails {
+		sd := &su.SeatDetails[i]
+		for j := range sm.Decks {
+			d := &sm.Decks[j]
+			r, c := d.row(sd.Row), d.column(sd.Column)
+			if r == nil || c == -1 {
+				continue
+			}
+			s := &Seat{}
+			s.make(sd)
+			for _, rc := range sd.RowCharacteristics {
+				if rc.Code == "E" {
+					r.ExitRow = true
+				}
+			}
+			r.Seats[c] = s
+			break
+		}
+	}
+	sort.SliceStable(sm.Decks, func(i, j int) bool {
+		return sm.Decks[i].lastRowNumber() < sm.Decks[j].lastRowNumber()
+	})
+	return nil
+}
+
+// Grid renders every deck of the seat map using Deck.Grid, separating them with an empty line.
+func (sm *SeatMap) Grid() string {
+	grids := make([]string, len(sm.Decks))
+	for i := range sm.Decks {
+		grids[i] = sm.Decks[i].Grid()
+	}
+	return strings.Join(grids, "\n\n")
+}
+
+func (sm *SeatMap) String() string {
+	return util.Stringer.Stringify(sm, "")
+}
+
+// FetchSeatMap requests the seat map of the given flight, for the given cabin class. The flight number must include
+// the airline code (for example LH400), and origin and destination are IATA airport codes. Only the date part of the
+// date parameter is used.
+// Lufthansa API documentation: https://developer.lufthansa.com/docs/read/api_details/offers/Seat_Maps
+func (a *API) FetchSeatMap(ctx context.Context, flightNumber, origin, destination string, date time.Time, cabin CabinClass) (*SeatMap, error) {
+	url := fmt.Sprintf("%s/seatmaps/%s/%s/%s/%s/%s", offersAPI, flightNumber, origin, destination, date.Format(dateLayout), cabin)
+	fetched, err := a.fetch(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+
+	sm := &SeatMap{Cabin: cabin}
+	return sm, sm.decode(fetched)
+}
@@ -0,0 +1,43 @@
+package lufthansa_test
+
+import (
+	"testing"
+	"time"
+
+	lufthansa "github.com/tmaxmax/lufthansaapi"
+)
+
+func TestAPI_FetchSeatMap(t *testing.T) {
+	sm, err := api.FetchSeatMap(ctx, "LH400", "FRA", "JFK", time.Now().AddDate(0, 0, 7), lufthansa.CabinEconomy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("%s\n%s", sm, sm.Grid())
+}
+
+func TestDeck_Grid(t *testing.T) {
+	d := &lufthansa.Deck{
+		Columns: []lufthansa.SeatColumn{
+			{Letter: "A", Window: true},
+			{Letter: "C", Aisle: true},
+			{Letter: "D", Aisle: true},
+			{Letter: "F", Window: true},
+		},
+		Rows: []lufthansa.SeatRow{
+			{Number: 9, Seats: []*lufthansa.Seat{{}, {}, {}, {}}},
+			{Number: 10, ExitRow: true, Seats: []*lufthansa.Seat{
+				{Characteristics: lufthansa.SeatExitRow | lufthansa.SeatWindow},
+				{Characteristics: lufthansa.SeatBlocked},
+				nil,
+				{Characteristics: lufthansa.SeatBassinet},
+			}},
+		},
+	}
+	expected := "   AC DF\n 9 oo oo\n10 EX  B"
+	if got := d.Grid(); got != expected {
+		t.Fatalf("expected:\n%s\ngot:\n%s", expected, got)
+	}
+	if d.Seat(10, "C") == nil || d.Seat(10, "D") != nil || d.Seat(11, "A") != nil {
+		t.Fatal("unexpected seat lookup result")
+	}
+}
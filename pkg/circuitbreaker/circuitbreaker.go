@@ -0,0 +1,236 @@
+// Package circuitbreaker implements a circuit breaker, which stops calls to a failing service for a while, so that
+// callers fail fast instead of waiting for the service, and lets a few calls through afterwards to check whether
+// the service recovered.
+package circuitbreaker
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// State is the state of a Breaker.
+type State uint8
+
+const (
+	// Closed lets all calls through, counting the consecutive failures.
+	Closed State = iota
+	// Open rejects all calls, until the open timeout passes.
+	Open
+	// HalfOpen lets a limited number of trial calls through: a success closes the breaker, a failure opens it again.
+	HalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case Closed:
+		return "closed"
+	case Open:
+		return "open"
+	case HalfOpen:
+		return "half-open"
+	default:
+		return fmt.Sprintf("State(%d)", uint8(s))
+	}
+}
+
+// Outcome is the result of a call, as reported to the Breaker.
+type Outcome uint8
+
+const (
+	// Success is a call that completed without a service failure.
+	Success Outcome = iota
+	// Failure is a call that failed because of the service, counting towards opening the breaker.
+	Failure
+	// Ignored is a call whose result tells nothing about the service, for example one canceled by the caller.
+	Ignored
+	// ServerError is a failure because the service responded with an error of its own. It counts towards
+	// Config.ServerErrors, besides Config.Failures.
+	ServerError
+	// Timeout is a failure because the service didn't respond in time. It counts towards Config.Timeouts,
+	// besides Config.Failures.
+	Timeout
+)
+
+func (o Outcome) failure() bool {
+	return o == Failure || o == ServerError || o == Timeout
+}
+
+// ErrOpen is matched by the errors returned when the breaker rejects a call. Check for it using errors.Is.
+var ErrOpen = errors.New("circuitbreaker: circuit is open")
+
+// OpenError is returned when the breaker rejects a call. It matches ErrOpen.
+type OpenError struct {
+	// RetryAfter is the time after which the breaker lets calls through again. It is 0 if the breaker is half-open
+	// and all the trial calls are in progress.
+	RetryAfter time.Duration
+}
+
+func (e *OpenError) Error() string {
+	return fmt.Sprintf("%s: retry after %s", ErrOpen, e.RetryAfter)
+}
+
+// Is reports whether the target is ErrOpen.
+func (e *OpenError) Is(target error) bool {
+	return target == ErrOpen
+}
+
+// Config configures a Breaker. The zero value uses the defaults.
+type Config struct {
+	// Failures is the number of consecutive failures of any kind that open the breaker. Default is 5.
+	Failures int
+	// ServerErrors is the number of ServerError outcomes that open the breaker, if no success comes between them.
+	// Default is Failures; values above it have no effect.
+	ServerErrors int
+	// Timeouts is the number of Timeout outcomes that open the breaker, if no success comes between them.
+	// Default is Failures; values above it have no effect.
+	Timeouts int
+	// OpenTimeout is the time the breaker stays open before letting trial calls through. Default is 30 seconds.
+	OpenTimeout time.Duration
+	// HalfOpenCalls is the number of trial calls let through at a time while half-open. Default is 1.
+	HalfOpenCalls int
+	// OnStateChange, if not nil, is called on every state change. It is called synchronously,
+	// so it must not block, and it must not call the Breaker's methods.
+	OnStateChange func(from, to State)
+}
+
+// Breaker is a circuit breaker. Create it with New. It is safe for concurrent use.
+type Breaker struct {
+	cfg Config
+	now func() time.Time
+
+	mu       sync.Mutex
+	state    State
+	failures int
+	// serverErrors and timeouts count the consecutive failures of their kind; the other failures don't reset them.
+	serverErrors int
+	timeouts     int
+	openedAt     time.Time
+	trials       int
+}
+
+// New creates a closed Breaker.
+func New(cfg Config) *Breaker {
+	if cfg.Failures <= 0 {
+		cfg.Failures = 5
+	}
+	if cfg.ServerErrors <= 0 {
+		cfg.ServerErrors = cfg.Failures
+	}
+	if cfg.Timeouts <= 0 {
+		cfg.Timeouts = cfg.Failures
+	}
+	if cfg.OpenTimeout <= 0 {
+		cfg.OpenTimeout = 30 * time.Second
+	}
+	if cfg.HalfOpenCalls <= 0 {
+		cfg.HalfOpenCalls = 1
+	}
+	return &Breaker{cfg: cfg, now: time.Now}
+}
+
+// setState changes the state and returns a function that notifies the change, to be called after
+// the lock is released. The caller must hold b.mu.
+func (b *Breaker) setState(to State) func() {
+	from := b.state
+	if from == to {
+		return func() {}
+	}
+	b.state = to
+	switch to {
+	case Open:
+		b.openedAt = b.now()
+	case Closed:
+		b.failures, b.serverErrors, b.timeouts = 0, 0, 0
+	case HalfOpen:
+		b.trials = 0
+	}
+	if b.cfg.OnStateChange == nil {
+		return func() {}
+	}
+	return func() { b.cfg.OnStateChange(from, to) }
+}
+
+// Allow checks whether a call may be made. If it may, the returned function must be called with the outcome
+// of the call; otherwise, an OpenError is returned.
+func (b *Breaker) Allow() (done func(Outcome), err error) {
+	b.mu.Lock()
+	notify := func() {}
+	if b.state == Open {
+		if left := b.openedAt.Add(b.cfg.OpenTimeout).Sub(b.now()); left > 0 {
+			b.mu.Unlock()
+			return nil, &OpenError{RetryAfter: left}
+		}
+		notify = b.setState(HalfOpen)
+	}
+	trial := b.state == HalfOpen
+	if trial {
+		if b.trials >= b.cfg.HalfOpenCalls {
+			b.mu.Unlock()
+			notify()
+			return nil, &OpenError{}
+		}
+		b.trials++
+	}
+	b.mu.Unlock()
+	notify()
+
+	var once sync.Once
+	return func(o Outcome) {
+		once.Do(func() { b.done(o, trial) })
+	}, nil
+}
+
+// done records the outcome of a call. Only the outcomes of the calls made in the current state matter:
+// the outcome of a call made while closed is ignored if the breaker opened meanwhile, for example.
+func (b *Breaker) done(o Outcome, trial bool) {
+	b.mu.Lock()
+	notify := func() {}
+	switch {
+	case !trial && b.state == Closed:
+		switch {
+		case o == Success:
+			b.failures, b.serverErrors, b.timeouts = 0, 0, 0
+		case o.failure():
+			b.failures++
+			if o == ServerError {
+				b.serverErrors++
+			}
+			if o == Timeout {
+				b.timeouts++
+			}
+			if b.failures >= b.cfg.Failures || b.serverErrors >= b.cfg.ServerErrors || b.timeouts >= b.cfg.Timeouts {
+				notify = b.setState(Open)
+			}
+		}
+	case trial && b.state == HalfOpen:
+		b.trials--
+		switch {
+		case o == Success:
+			notify = b.setState(Closed)
+		case o.failure():
+			notify = b.setState(Open)
+		}
+	}
+	b.mu.Unlock()
+	notify()
+}
+
+// Report records the outcome of something that failed after its call was already reported done, like reading
+// the rest of a response. It counts like the outcome of a call allowed while closed, so it doesn't take or give
+// back a half-open trial.
+func (b *Breaker) Report(o Outcome) {
+	b.done(o, false)
+}
+
+// State returns the current state of the breaker. An open breaker whose timeout passed is reported as half-open.
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == Open && !b.now().Before(b.openedAt.Add(b.cfg.OpenTimeout)) {
+		return HalfOpen
+	}
+	return b.state
+}
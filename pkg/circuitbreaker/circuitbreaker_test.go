@@ -0,0 +1,151 @@
+package circuitbreaker
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBreaker(t *testing.T) {
+	now := time.Date(2020, 8, 26, 12, 0, 0, 0, time.UTC)
+	var changes []string
+	b := New(Config{
+		Failures:    3,
+		OpenTimeout: time.Minute,
+		OnStateChange: func(from, to State) {
+			changes = append(changes, from.String()+"->"+to.String())
+		},
+	})
+	b.now = func() time.Time { return now }
+
+	call := func(o Outcome) error {
+		done, err := b.Allow()
+		if err != nil {
+			return err
+		}
+		done(o)
+		return nil
+	}
+
+	for _, o := range []Outcome{Failure, Failure, Success, Failure, Ignored, Failure} {
+		if err := call(o); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if s := b.State(); s != Closed {
+		t.Fatalf("expected a success to reset the failures, got state %s", s)
+	}
+	if err := call(Failure); err != nil {
+		t.Fatal(err)
+	}
+	if s := b.State(); s != Open {
+		t.Fatalf("expected 3 consecutive failures to open the breaker, got state %s", s)
+	}
+
+	err := call(Success)
+	var oe *OpenError
+	if !errors.Is(err, ErrOpen) || !errors.As(err, &oe) || oe.RetryAfter != time.Minute {
+		t.Fatalf("expected an open error with a minute to wait, got %v", err)
+	}
+
+	now = now.Add(time.Minute)
+	done, err := b.Allow()
+	if err != nil {
+		t.Fatalf("expected a trial call, got %v", err)
+	}
+	if _, err = b.Allow(); !errors.Is(err, ErrOpen) {
+		t.Fatalf("expected only one trial call, got %v", err)
+	}
+	done(Failure)
+	if s := b.State(); s != Open {
+		t.Fatalf("expected a failed trial to open the breaker, got state %s", s)
+	}
+
+	now = now.Add(time.Minute)
+	if err = call(Success); err != nil {
+		t.Fatal(err)
+	}
+	if s := b.State(); s != Closed {
+		t.Fatalf("expected a successful trial to close the breaker, got state %s", s)
+	}
+
+	want := []string{"closed->open", "open->half-open", "half-open->open", "open->half-open", "half-open->closed"}
+	if len(changes) != len(want) {
+		t.Fatalf("expected state changes %v, got %v", want, changes)
+	}
+	for i := range want {
+		if changes[i] != want[i] {
+			t.Fatalf("expected state changes %v, got %v", want, changes)
+		}
+	}
+}
+
+func TestBreaker_StaleOutcomes(t *testing.T) {
+	b := New(Config{Failures: 1})
+	stale, _ := b.Allow()
+	if done, err := b.Allow(); err == nil {
+		done(Failure)
+	}
+	if b.State() != Open {
+		t.Fatal("expected the breaker to open")
+	}
+	stale(Success)
+	if b.State() != Open {
+		t.Fatal("expected the outcome of a call made before opening to be ignored")
+	}
+}
+
+func TestBreaker_Thresholds(t *testing.T) {
+	tests := []struct {
+		name     string
+		outcomes []Outcome
+		state    State
+	}{
+		{"timeouts", []Outcome{Timeout, Timeout}, Open},
+		{"timeouts with other failures", []Outcome{Timeout, ServerError, Failure, Timeout}, Open},
+		{"timeouts reset by success", []Outcome{Timeout, Success, Timeout}, Closed},
+		{"server errors", []Outcome{ServerError, ServerError, ServerError}, Open},
+		{"few server errors", []Outcome{ServerError, ServerError, Timeout}, Closed},
+		{"failures", []Outcome{Failure, ServerError, Failure, Timeout, Failure}, Open},
+	}
+	for _, test := range tests {
+		b := New(Config{Failures: 5, ServerErrors: 3, Timeouts: 2})
+		for _, o := range test.outcomes {
+			done, err := b.Allow()
+			if err != nil {
+				t.Fatalf("%s: %v", test.name, err)
+			}
+			done(o)
+		}
+		if s := b.State(); s != test.state {
+			t.Errorf("%s: expected state %s, got %s", test.name, test.state, s)
+		}
+	}
+}
+
+func TestBreaker_Report(t *testing.T) {
+	now := time.Date(2020, 8, 26, 12, 0, 0, 0, time.UTC)
+	b := New(Config{Failures: 2, OpenTimeout: time.Minute})
+	b.now = func() time.Time { return now }
+
+	b.Report(Failure)
+	b.Report(Timeout)
+	if s := b.State(); s != Open {
+		t.Fatalf("expected the reported failures to open the breaker, got state %s", s)
+	}
+
+	now = now.Add(time.Minute)
+	done, err := b.Allow()
+	if err != nil {
+		t.Fatal(err)
+	}
+	done(Success)
+	// the trial is over, so a failure reported for it afterwards counts like any other
+	b.Report(Failure)
+	if s := b.State(); s != Closed {
+		t.Fatalf("expected a single failure to leave the breaker closed, got state %s", s)
+	}
+	if _, err = b.Allow(); err != nil {
+		t.Fatalf("expected a call to be allowed, got %v", err)
+	}
+}
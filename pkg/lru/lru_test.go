@@ -0,0 +1,51 @@
+package lru
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCache_Eviction(t *testing.T) {
+	c := New(2)
+	c.Set("a", []byte("1"), time.Hour)
+	c.Set("b", []byte("2"), time.Hour)
+	if _, ok := c.Get("a"); !ok {
+		t.Fatal("expected a to be cached")
+	}
+	c.Set("c", []byte("3"), time.Hour)
+
+	if _, ok := c.Get("b"); ok {
+		t.Fatal("expected b, the least recently used entry, to be evicted")
+	}
+	for _, k := range []string{"a", "c"} {
+		if _, ok := c.Get(k); !ok {
+			t.Fatalf("expected %s to be cached", k)
+		}
+	}
+	if c.Len() != 2 {
+		t.Fatalf("expected 2 entries, got %d", c.Len())
+	}
+}
+
+func TestCache_Expiration(t *testing.T) {
+	now := time.Date(2020, time.August, 26, 12, 0, 0, 0, time.UTC)
+	c := New(10)
+	c.now = func() time.Time { return now }
+
+	c.Set("a", []byte("1"), time.Minute)
+	c.Set("b", []byte("2"), 0)
+	if v, ok := c.Get("a"); !ok || string(v) != "1" {
+		t.Fatalf("unexpected value %q, %t", v, ok)
+	}
+	if _, ok := c.Get("b"); ok {
+		t.Fatal("expected zero TTL entries not to be stored")
+	}
+
+	now = now.Add(time.Minute)
+	if _, ok := c.Get("a"); ok {
+		t.Fatal("expected a to be expired")
+	}
+	if c.Len() != 0 {
+		t.Fatalf("expected expired entry to be removed, got %d entries", c.Len())
+	}
+}
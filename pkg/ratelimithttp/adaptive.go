@@ -0,0 +1,250 @@
+package ratelimithttp
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+const (
+	// minFactor is the lowest fraction of the configured rates the limiters are slowed down to.
+	minFactor = 1.0 / 64
+	// defaultRetryAfter is the pause applied on a 429 response without a Retry-After header.
+	defaultRetryAfter = time.Second
+)
+
+// RateReport describes the current state of a Transport's limiters.
+type RateReport struct {
+	// Limits are the current rates of the limiters, in the order they were given.
+	Limits []rate.Limit
+	// Factor is the fraction of the configured rates the limiters currently run at, 1 meaning full speed.
+	Factor float64
+	// PausedUntil is the time until which no requests are sent, if it is in the future.
+	PausedUntil time.Time
+	// Groups are the reports of the limiter groups added with WithGroup, by name. With WithAdaptive, every group
+	// is slowed down and paused separately, by the responses to its own requests.
+	Groups map[string]RateReport
+}
+
+// adaptive slows down a Transport's limiters when the server signals that the client is sending too many requests.
+type adaptive struct {
+	mu              sync.Mutex
+	limiters        []*rate.Limiter
+	base            []rate.Limit
+	rampUp          time.Duration
+	remainingHeader string
+	resetHeader     string
+	pausedUntil     time.Time
+	// floor is the factor the limiters were slowed down to by the last throttle, which is ramped up back to 1.
+	floor  float64
+	factor float64
+	now    func() time.Time
+}
+
+// WithAdaptive makes the Transport adjust its limiters to the server's responses:
+//   - a 429 Too Many Requests response pauses all requests for the duration given by its Retry-After header
+//     (one second, if missing) and halves the limiters' rates
+//   - a 503 Service Unavailable response with a Retry-After header pauses all requests
+//   - a response whose quota remaining header is 0 pauses all requests until the time given by the quota reset header
+//
+// After a pause, the rates are ramped back up linearly to the configured ones over the given duration.
+// The responses to the requests of a group added with WithGroup pause only the group's requests, and adjust
+// the group's limiters instead of the Transport's.
+// The quota headers are X-RateLimit-Remaining and X-RateLimit-Reset by default; use WithQuotaHeaders to change them.
+func WithAdaptive(rampUp time.Duration) Option {
+	return func(t *Transport) {
+		if t.adaptive == nil {
+			t.adaptive = &adaptive{
+				remainingHeader: "X-RateLimit-Remaining",
+				resetHeader:     "X-RateLimit-Reset",
+				floor:           1,
+				factor:          1,
+				now:             time.Now,
+			}
+		}
+		t.adaptive.rampUp = rampUp
+	}
+}
+
+// WithQuotaHeaders sets the names of the response headers holding the number of requests remaining in the current
+// quota period and the time the quota resets, either as seconds from now or as a Unix timestamp. It has effect only
+// together with WithAdaptive, and it can be given before or after it.
+func WithQuotaHeaders(remaining, reset string) Option {
+	return func(t *Transport) {
+		t.quotaHeaders = []string{remaining, reset}
+	}
+}
+
+// clone returns an adaptive with the same settings, for other limiters, which must be given to init.
+func (a *adaptive) clone() *adaptive {
+	return &adaptive{
+		rampUp:          a.rampUp,
+		remainingHeader: a.remainingHeader,
+		resetHeader:     a.resetHeader,
+		floor:           1,
+		factor:          1,
+		now:             a.now,
+	}
+}
+
+func (a *adaptive) init(limiters []*rate.Limiter) {
+	a.limiters = limiters
+	a.base = make([]rate.Limit, len(limiters))
+	for i, l := range limiters {
+		a.base[i] = l.Limit()
+	}
+}
+
+// adjust ramps the limiters' rates up, if the pause is over. The caller must hold a.mu.
+func (a *adaptive) adjust(now time.Time) {
+	if a.factor >= 1 || now.Before(a.pausedUntil) {
+		return
+	}
+	factor := 1.0
+	if elapsed := now.Sub(a.pausedUntil); a.rampUp > 0 && elapsed < a.rampUp {
+		factor = a.floor + (1-a.floor)*float64(elapsed)/float64(a.rampUp)
+	}
+	a.setFactor(now, factor)
+}
+
+// setFactor sets the limiters' rates to the given fraction of the configured ones. The caller must hold a.mu.
+func (a *adaptive) setFactor(now time.Time, factor float64) {
+	a.factor = factor
+	for i, l := range a.limiters {
+		if a.base[i] == rate.Inf {
+			continue
+		}
+		l.SetLimitAt(now, a.base[i]*rate.Limit(factor))
+	}
+}
+
+// pause stops all requests until the given time, optionally halving the rates.
+func (a *adaptive) pause(until time.Time, slowDown bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := a.now()
+	a.adjust(now)
+	if until.After(a.pausedUntil) {
+		a.pausedUntil = until
+	}
+	if slowDown {
+		a.floor = a.factor / 2
+		if a.floor < minFactor {
+			a.floor = minFactor
+		}
+		a.setFactor(now, a.floor)
+	} else if a.factor < 1 {
+		// the ramp up restarts after the new pause, from where it is now
+		a.floor = a.factor
+	}
+}
+
+// pauseLeft returns the time left until the pause is over.
+func (a *adaptive) pauseLeft() time.Duration {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := a.now()
+	a.adjust(now)
+	if d := a.pausedUntil.Sub(now); d > 0 {
+		return d
+	}
+	return 0
+}
+
+// observe adjusts the limiters according to the response.
+func (a *adaptive) observe(res *http.Response) {
+	now := a.now()
+	switch res.StatusCode {
+	case http.StatusTooManyRequests:
+		d, ok := parseRetryAfter(res.Header.Get("Retry-After"), now)
+		if !ok {
+			d = defaultRetryAfter
+		}
+		a.pause(now.Add(d), true)
+		return
+	case http.StatusServiceUnavailable:
+		if d, ok := parseRetryAfter(res.Header.Get("Retry-After"), now); ok {
+			a.pause(now.Add(d), false)
+			return
+		}
+	}
+	if remaining, err := strconv.Atoi(res.Header.Get(a.remainingHeader)); err == nil && remaining <= 0 {
+		if reset, ok := parseReset(res.Header.Get(a.resetHeader), now); ok {
+			a.pause(reset, false)
+		}
+	}
+}
+
+func (a *adaptive) report() RateReport {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.adjust(a.now())
+	r := RateReport{
+		Limits:      make([]rate.Limit, len(a.limiters)),
+		Factor:      a.factor,
+		PausedUntil: a.pausedUntil,
+	}
+	for i, l := range a.limiters {
+		r.Limits[i] = l.Limit()
+	}
+	return r
+}
+
+// parseRetryAfter parses the value of a Retry-After header, which is either a number of seconds or an HTTP date.
+func parseRetryAfter(v string, now time.Time) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	if s, err := strconv.Atoi(v); err == nil {
+		return time.Duration(s) * time.Second, s >= 0
+	}
+	t, err := http.ParseTime(v)
+	if err != nil {
+		return 0, false
+	}
+	return t.Sub(now), true
+}
+
+// parseReset parses a quota reset header, which is either a number of seconds from now or a Unix timestamp.
+func parseReset(v string, now time.Time) (time.Time, bool) {
+	s, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || s < 0 {
+		return time.Time{}, false
+	}
+	// a number of seconds bigger than a year can't be a delay
+	if s > 365*24*60*60 {
+		return time.Unix(s, 0), true
+	}
+	return now.Add(time.Duration(s) * time.Second), true
+}
+
+// rateReport reports the rates of the limiters, adjusted by a if it isn't nil.
+func rateReport(limiters []*rate.Limiter, a *adaptive) RateReport {
+	if a != nil {
+		return a.report()
+	}
+	r := RateReport{Limits: make([]rate.Limit, len(limiters)), Factor: 1}
+	for i, l := range limiters {
+		r.Limits[i] = l.Limit()
+	}
+	return r
+}
+
+// EffectiveRate reports the current rates of the Transport's limiters and of its groups' limiters. Without
+// WithAdaptive, they are always the configured ones.
+func (t *Transport) EffectiveRate() RateReport {
+	r := rateReport(t.limiters, t.adaptive)
+	if len(t.groups) > 0 {
+		r.Groups = make(map[string]RateReport, len(t.groups))
+		for _, g := range t.groups {
+			r.Groups[g.name] = rateReport(g.limiters, g.adaptive)
+		}
+	}
+	return r
+}
@@ -0,0 +1,91 @@
+package ratelimithttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+func TestAdaptive_TooManyRequests(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "30")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	now := time.Date(2020, 8, 26, 12, 0, 0, 0, time.UTC)
+	tr := NewTransport(nil, WithLimiters(rate.NewLimiter(10, 10)), WithAdaptive(time.Minute))
+	tr.adaptive.now = func() time.Time { return now }
+
+	res, err := (&http.Client{Transport: tr}).Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+
+	r := tr.EffectiveRate()
+	if r.Factor != 0.5 || r.Limits[0] != 5 {
+		t.Fatalf("expected the rate to be halved, got %+v", r)
+	}
+	if !r.PausedUntil.Equal(now.Add(30 * time.Second)) {
+		t.Fatalf("expected pause until %v, got %v", now.Add(30*time.Second), r.PausedUntil)
+	}
+
+	now = now.Add(30*time.Second + 30*time.Second)
+	if r = tr.EffectiveRate(); r.Factor != 0.75 || r.Limits[0] != 7.5 {
+		t.Fatalf("expected the rate to be half way ramped up, got %+v", r)
+	}
+	now = now.Add(time.Hour)
+	if r = tr.EffectiveRate(); r.Factor != 1 || r.Limits[0] != 10 {
+		t.Fatalf("expected the rate to be fully restored, got %+v", r)
+	}
+}
+
+func TestAdaptive_QuotaHeaders(t *testing.T) {
+	now := time.Date(2020, 8, 26, 12, 0, 0, 0, time.UTC)
+	a := NewTransport(nil, WithAdaptive(time.Minute), WithQuotaHeaders("X-Quota-Remaining", "X-Quota-Reset"))
+	if a.adaptive.rampUp != time.Minute {
+		t.Fatalf("expected the ramp up duration to be kept, got %s", a.adaptive.rampUp)
+	}
+	a.adaptive.now = func() time.Time { return now }
+
+	header := http.Header{}
+	header.Set("X-Quota-Remaining", "0")
+	header.Set("X-Quota-Reset", "120")
+	a.adaptive.observe(&http.Response{StatusCode: http.StatusOK, Header: header})
+	if r := a.EffectiveRate(); !r.PausedUntil.Equal(now.Add(2*time.Minute)) || r.Factor != 1 {
+		t.Fatalf("expected a pause of 2 minutes at full rate, got %+v", r)
+	}
+}
+
+func TestAdaptive_QuotaHeadersWithoutAdaptive(t *testing.T) {
+	if a := NewTransport(nil, WithQuotaHeaders("X-Quota-Remaining", "X-Quota-Reset")); a.adaptive != nil {
+		t.Fatal("expected WithQuotaHeaders alone not to enable the adaptive behavior")
+	}
+	a := NewTransport(nil, WithQuotaHeaders("X-Quota-Remaining", "X-Quota-Reset"), WithAdaptive(time.Minute))
+	if a.adaptive.rampUp != time.Minute || a.adaptive.remainingHeader != "X-Quota-Remaining" || a.adaptive.resetHeader != "X-Quota-Reset" {
+		t.Fatalf("unexpected adaptive settings %+v", a.adaptive)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2020, 8, 26, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value string
+		want  time.Duration
+		ok    bool
+	}{
+		{"120", 2 * time.Minute, true},
+		{now.Add(time.Minute).Format(http.TimeFormat), time.Minute, true},
+		{"", 0, false},
+		{"soon", 0, false},
+	}
+	for _, tt := range tests {
+		if got, ok := parseRetryAfter(tt.value, now); got != tt.want || ok != tt.ok {
+			t.Errorf("parseRetryAfter(%q) = %v, %t, want %v, %t", tt.value, got, ok, tt.want, tt.ok)
+		}
+	}
+}
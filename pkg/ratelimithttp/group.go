@@ -0,0 +1,78 @@
+package ratelimithttp
+
+import (
+	"net/http"
+	"strings"
+
+	"golang.org/x/time/rate"
+)
+
+// Matcher reports whether a request belongs to a limiter group.
+type Matcher func(*http.Request) bool
+
+// PathPrefix returns a Matcher for the requests whose URL path starts with one of the given prefixes. A prefix
+// matches whole path segments: "/v1/operations" matches "/v1/operations/schedules", but not "/v1/operationsx".
+func PathPrefix(prefixes ...string) Matcher {
+	return func(req *http.Request) bool {
+		path := req.URL.Path
+		for _, prefix := range prefixes {
+			prefix = strings.TrimRight(prefix, "/")
+			if path == prefix || strings.HasPrefix(path, prefix+"/") {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// group is a set of limiters that apply only to the requests it matches, with its own queue, so that its requests
+// waiting for its limiters don't hold back the requests of the other groups.
+type group struct {
+	name     string
+	match    Matcher
+	limiters []*rate.Limiter
+	sched    scheduler
+	// adaptive is the state adjusted by the responses to the group's requests, if WithAdaptive is used.
+	adaptive *adaptive
+}
+
+// WithGroup adds a limiter group to the Transport: the requests matched by match must be allowed by the given limiters
+// as well, besides the Transport's own limiters. The requests of a group first wait in a separate queue for the
+// group's limiters, so a burst of requests for one group doesn't delay the others more than the Transport's own
+// limiters require, and then in the Transport's queue, together with all the other requests, for the Transport's
+// limiters. A request takes the group's tokens only once the Transport's limiters have a token for it. A request
+// belongs to the first group that matches it, in the order the groups were added. With WithAdaptive, the responses
+// to a group's requests pause and adjust only the group.
+func WithGroup(name string, match Matcher, limiters ...*rate.Limiter) Option {
+	return func(t *Transport) {
+		t.groups = append(t.groups, &group{
+			name:     name,
+			match:    match,
+			limiters: limiters,
+		})
+	}
+}
+
+// groupOf returns the group of the request, or nil if it doesn't belong to any.
+func (t *Transport) groupOf(req *http.Request) *group {
+	for _, g := range t.groups {
+		if g.match(req) {
+			return g
+		}
+	}
+	return nil
+}
+
+// stageOf returns the stage of the group's own limiters.
+func (t *Transport) stageOf(g *group) *stage {
+	return &stage{sched: &g.sched, limiters: g.limiters, adaptive: g.adaptive, shares: t.shares}
+}
+
+// adaptiveFor returns the adaptive state that the responses to the group's requests adjust, which is the Transport's
+// one for the requests that don't belong to a group. It returns nil if WithAdaptive wasn't used.
+func (t *Transport) adaptiveFor(g *group) *adaptive {
+	if g != nil {
+		return g.adaptive
+	}
+	return t.adaptive
+}
@@ -0,0 +1,186 @@
+package ratelimithttp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+func TestTransport_Groups(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	schedules := rate.NewLimiter(rate.Every(time.Hour), 1)
+	schedules.Allow()
+	tr := NewTransport(nil,
+		WithLimiters(rate.NewLimiter(rate.Inf, 0)),
+		WithGroup("operations", PathPrefix("/v1/operations"), schedules),
+	)
+	client := &http.Client{Transport: tr}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() {
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/v1/operations/schedules/FRA/JFK", nil)
+		_, err := client.Do(req)
+		errc <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	if q := tr.Stats().Queued; q != 1 {
+		t.Fatalf("expected the operations request to be queued, got %d queued", q)
+	}
+
+	start := time.Now()
+	res, err := client.Get(srv.URL + "/v1/mds-references/airports/FRA")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("expected the reference request not to wait for the operations group, waited %s", elapsed)
+	}
+
+	cancel()
+	if err = <-errc; err == nil {
+		t.Fatal("expected the operations request to be canceled")
+	}
+	if gt := tr.Stats().GroupTokens["operations"]; len(gt) != 1 || gt[0] > 0.01 {
+		t.Fatalf("expected the operations group to have no tokens, got %v", gt)
+	}
+}
+
+func TestPathPrefix(t *testing.T) {
+	match := PathPrefix("/v1/operations/")
+	for path, want := range map[string]bool{
+		"/v1/operations":              true,
+		"/v1/operations/flightstatus": true,
+		"/v1/operationsx":             false,
+		"/v1/mds-references":          false,
+	} {
+		req, _ := http.NewRequest(http.MethodGet, "https://api.lufthansa.com"+path, nil)
+		if got := match(req); got != want {
+			t.Errorf("match(%q) = %t, want %t", path, got, want)
+		}
+	}
+}
+
+func TestTransport_GroupsSharePriorities(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		order []string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		order = append(order, r.URL.Path)
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	// the first token of the shared limiter arrives long after both requests are queued
+	shared := rate.NewLimiter(rate.Every(300*time.Millisecond), 1)
+	shared.Allow()
+	tr := NewTransport(nil,
+		WithLimiters(shared),
+		WithGroup("operations", PathPrefix("/v1/operations"), rate.NewLimiter(rate.Inf, 0)),
+	)
+	client := &http.Client{Transport: tr}
+
+	var wg sync.WaitGroup
+	send := func(path string, p Priority) {
+		defer wg.Done()
+		req, _ := http.NewRequestWithContext(WithPriority(context.Background(), p), http.MethodGet, srv.URL+path, nil)
+		res, err := client.Do(req)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		res.Body.Close()
+	}
+	wg.Add(2)
+	go send("/v1/operations/schedules", PriorityLow)
+	for tr.sched.len() < 1 {
+		time.Sleep(time.Millisecond)
+	}
+	go send("/v1/mds-references/airports", PriorityHigh)
+	for tr.sched.len() < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	wg.Wait()
+
+	if len(order) != 2 || order[0] != "/v1/mds-references/airports" {
+		t.Fatalf("expected the high priority request to be sent first, got %v", order)
+	}
+}
+
+func TestTransport_GroupsAdaptive(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/v1/operations") {
+			w.Header().Set("Retry-After", "60")
+			w.WriteHeader(http.StatusTooManyRequests)
+		}
+	}))
+	defer srv.Close()
+
+	tr := NewTransport(nil,
+		WithLimiters(rate.NewLimiter(rate.Inf, 0)),
+		WithAdaptive(time.Minute),
+		WithGroup("operations", PathPrefix("/v1/operations"), rate.NewLimiter(10, 1)),
+	)
+	client := &http.Client{Transport: tr}
+	res, err := client.Get(srv.URL + "/v1/operations/schedules")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+
+	r := tr.EffectiveRate()
+	if gr := r.Groups["operations"]; time.Until(gr.PausedUntil) < 59*time.Second || gr.Factor != 0.5 || gr.Limits[0] != 5 {
+		t.Fatalf("expected the operations group to be paused for a minute and slowed down to 5, got %+v", gr)
+	}
+	if !r.PausedUntil.IsZero() || r.Factor != 1 {
+		t.Fatalf("expected the Transport not to be paused, got %+v", r)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/v1/mds-references/airports", nil)
+	if res, err = client.Do(req); err != nil {
+		t.Fatalf("expected the other requests not to be paused, got %v", err)
+	}
+	res.Body.Close()
+}
+
+func TestTransport_GroupsWaitForShared(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer srv.Close()
+
+	shared := rate.NewLimiter(rate.Every(200*time.Millisecond), 1)
+	shared.Allow()
+	operations := rate.NewLimiter(rate.Every(time.Hour), 1)
+	tr := NewTransport(nil, WithLimiters(shared), WithGroup("operations", PathPrefix("/v1/operations"), operations))
+
+	done := make(chan error)
+	go func() {
+		res, err := (&http.Client{Transport: tr}).Get(srv.URL + "/v1/operations/schedules")
+		if err == nil {
+			res.Body.Close()
+		}
+		done <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+	if tokens := operations.TokensAt(time.Now()); tokens < 1 {
+		t.Fatalf("expected the group's token not to be taken while the Transport's limiters are exhausted, got %f tokens", tokens)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if tokens := operations.TokensAt(time.Now()); tokens >= 1 {
+		t.Fatalf("expected the group's token to be taken, got %f tokens", tokens)
+	}
+}
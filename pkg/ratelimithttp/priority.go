@@ -0,0 +1,351 @@
+package ratelimithttp
+
+import (
+	"container/heap"
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Priority is the priority of a request. When the limiters are saturated, requests with higher priority are sent
+// first; requests with the same priority are sent in the order they were made.
+type Priority int
+
+// The predefined priorities. Any other value can be used, too.
+const (
+	// PriorityLow is meant for background work, like bulk synchronizations, which yields to everything else.
+	PriorityLow Priority = -1
+	// PriorityNormal is the priority of the requests whose context doesn't have one.
+	PriorityNormal Priority = 0
+	// PriorityHigh is meant for interactive work, like lookups done on behalf of a user.
+	PriorityHigh Priority = 1
+)
+
+type priorityKey struct{}
+
+// WithPriority returns a context that gives the requests made with it the given priority.
+func WithPriority(ctx context.Context, p Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, p)
+}
+
+// PriorityFrom returns the priority of the requests made with the context.
+func PriorityFrom(ctx context.Context) Priority {
+	p, ok := ctx.Value(priorityKey{}).(Priority)
+	if !ok {
+		return PriorityNormal
+	}
+	return p
+}
+
+// WithReservedShare reserves a share, between 0 and 1, of every limiter's burst for the requests of the given
+// priority or higher: requests of lower priority are sent only if that many tokens would remain available after them.
+// The shares of multiple priorities add up; for example, reserving 0.2 for PriorityHigh and 0.3 for PriorityNormal
+// makes PriorityLow requests leave half of the burst untouched.
+func WithReservedShare(p Priority, share float64) Option {
+	return func(t *Transport) {
+		if t.shares == nil {
+			t.shares = make(map[Priority]float64)
+		}
+		t.shares[p] = math.Max(0, math.Min(share, 1))
+	}
+}
+
+// waiter is a request waiting to be admitted.
+type waiter struct {
+	priority Priority
+	seq      uint64
+	index    int
+	// wake is signaled when the waiter becomes the head of the queue or the head changes.
+	wake chan struct{}
+}
+
+// waiters is a priority queue of waiters: the head is the one with the highest priority that came first.
+type waiters []*waiter
+
+func (w waiters) Len() int { return len(w) }
+
+func (w waiters) Less(i, j int) bool {
+	if w[i].priority != w[j].priority {
+		return w[i].priority > w[j].priority
+	}
+	return w[i].seq < w[j].seq
+}
+
+func (w waiters) Swap(i, j int) {
+	w[i], w[j] = w[j], w[i]
+	w[i].index = i
+	w[j].index = j
+}
+
+func (w *waiters) Push(x interface{}) {
+	wt := x.(*waiter)
+	wt.index = len(*w)
+	*w = append(*w, wt)
+}
+
+func (w *waiters) Pop() interface{} {
+	old := *w
+	wt := old[len(old)-1]
+	old[len(old)-1] = nil
+	*w = old[:len(old)-1]
+	return wt
+}
+
+// scheduler admits requests one at a time, in the order of their priorities. Only the head of the queue
+// waits for the limiters; the others wait to become the head.
+type scheduler struct {
+	mu    sync.Mutex
+	queue waiters
+	seq   uint64
+	// next is the time the head expects to be admitted at, or zero if unknown.
+	next time.Time
+}
+
+func wake(w *waiter) {
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (s *scheduler) enqueue(p Priority) *waiter {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	w := &waiter{priority: p, seq: s.seq, wake: make(chan struct{}, 1)}
+	s.seq++
+	var head *waiter
+	if len(s.queue) > 0 {
+		head = s.queue[0]
+	}
+	heap.Push(&s.queue, w)
+	if head != nil && s.queue[0] == w {
+		// the previous head must stop waiting for the limiters
+		s.next = time.Time{}
+		wake(head)
+	}
+	return w
+}
+
+func (s *scheduler) remove(w *waiter) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	wasHead := w.index == 0
+	heap.Remove(&s.queue, w.index)
+	if wasHead {
+		s.next = time.Time{}
+		if len(s.queue) > 0 {
+			wake(s.queue[0])
+		}
+	}
+}
+
+// head reports whether w is the head of the queue. If it isn't, the time the current head expects to be
+// admitted at is also returned.
+func (s *scheduler) head(w *waiter) (time.Time, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.next, s.queue[0] == w
+}
+
+func (s *scheduler) len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.queue)
+}
+
+func (s *scheduler) setNext(next time.Time) {
+	s.mu.Lock()
+	s.next = next
+	s.mu.Unlock()
+}
+
+// reservedTokens returns the number of tokens of a bucket with the given burst a request of priority p must leave
+// available, given the shares reserved for each priority. At least one token is always usable.
+func reservedTokens(shares map[Priority]float64, p Priority, burst int) int {
+	share := 0.0
+	for sp, s := range shares {
+		if sp > p {
+			share += s
+		}
+	}
+	n := int(math.Ceil(share * float64(burst)))
+	if n > burst-1 {
+		n = burst - 1
+	}
+	if n < 0 {
+		n = 0
+	}
+	return n
+}
+
+// delayFor returns the time after which the limiter has n tokens available, or rate.InfDuration if it never will.
+// The limiter's state is only read, so other users of the limiter aren't affected.
+func delayFor(l *rate.Limiter, now time.Time, n int) time.Duration {
+	limit := l.Limit()
+	if limit == rate.Inf {
+		return 0
+	}
+	if n > l.Burst() {
+		return rate.InfDuration
+	}
+	missing := float64(n) - l.TokensAt(now)
+	if missing <= 0 {
+		return 0
+	}
+	if limit <= 0 {
+		return rate.InfDuration
+	}
+	return time.Duration(missing / float64(limit) * float64(time.Second))
+}
+
+// stage is a set of limiters that admits requests one at a time, in the order of their priorities. The requests
+// of a group go through the group's stage and then through the Transport's one, so that the Transport's limiters
+// are shared fairly by all the requests.
+type stage struct {
+	sched      *scheduler
+	limiters   []*rate.Limiter
+	persistent []*PersistentLimiter
+	adaptive   *adaptive
+	shares     map[Priority]float64
+}
+
+// shared returns the stage of the Transport's own limiters.
+func (t *Transport) shared() *stage {
+	return &stage{sched: &t.sched, limiters: t.limiters, persistent: t.persistent, adaptive: t.adaptive, shares: t.shares}
+}
+
+// loadPersistent reads the states of the stage's persistent limiters, for admissionDelay.
+func (s *stage) loadPersistent() ([]persistedState, error) {
+	states := make([]persistedState, len(s.persistent))
+	for i, pl := range s.persistent {
+		ps, err := pl.load()
+		if err != nil {
+			return nil, err
+		}
+		states[i] = ps
+	}
+	return states, nil
+}
+
+// admissionDelay returns the time after which a request of priority p can be admitted: the stage isn't paused and
+// every limiter has a token for it, besides the ones reserved for higher priorities. The persistent limiters are
+// checked against the states read by loadPersistent. No tokens are taken.
+func (s *stage) admissionDelay(now time.Time, p Priority, persisted []persistedState) time.Duration {
+	var delay time.Duration
+	if s.adaptive != nil {
+		delay = s.adaptive.pauseLeft()
+	}
+	for _, rl := range s.limiters {
+		d := delayFor(rl, now, 1+reservedTokens(s.shares, p, rl.Burst()))
+		if d == rate.InfDuration {
+			return d
+		}
+		if d > delay {
+			delay = d
+		}
+	}
+	for i, pl := range s.persistent {
+		if d := pl.delayAt(persisted[i], now, 1+reservedTokens(s.shares, p, pl.burst)); d > delay {
+			delay = d
+		}
+	}
+	return delay
+}
+
+// wait admits the request once it is the one with the highest priority waiting in the stage and the limiters
+// allow it, then takes its tokens. If the wait would exceed the context's deadline, a QuotaError is returned
+// immediately. The persistent limiters' states are read once, when the request first reaches the head of the
+// queue; the tokens are taken from their current states anyway.
+func (s *stage) wait(ctx context.Context) (*reservation, error) {
+	return s.admit(ctx, true)
+}
+
+// ready waits like wait until the stage would admit the request, but then leaves the queue without taking any tokens.
+func (s *stage) ready(ctx context.Context) error {
+	_, err := s.admit(ctx, false)
+	return err
+}
+
+// admit implements wait and ready: the tokens are taken only if take is true.
+func (s *stage) admit(ctx context.Context, take bool) (*reservation, error) {
+	w := s.sched.enqueue(PriorityFrom(ctx))
+	defer s.sched.remove(w)
+
+	deadline, hasDeadline := ctx.Deadline()
+	var persisted []persistedState
+	for {
+		if next, ok := s.sched.head(w); !ok {
+			if hasDeadline && !next.IsZero() && next.After(deadline) {
+				return nil, &QuotaError{Wait: time.Until(next)}
+			}
+			select {
+			case <-w.wake:
+				continue
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+
+		if persisted == nil {
+			var err error
+			if persisted, err = s.loadPersistent(); err != nil {
+				return nil, err
+			}
+		}
+		now := time.Now()
+		delay := s.admissionDelay(now, w.priority, persisted)
+		if delay <= 0 {
+			if !take {
+				return nil, nil
+			}
+			return s.acquire(ctx)
+		}
+		if delay == rate.InfDuration {
+			return nil, &QuotaError{Wait: delay}
+		}
+		if hasDeadline && delay > deadline.Sub(now) {
+			return nil, &QuotaError{Wait: delay}
+		}
+		s.sched.setNext(now.Add(delay))
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-w.wake:
+			timer.Stop()
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// wait admits the request through the stage of its group, if it has one, and then through the Transport's stage.
+// A request of a group first waits in the Transport's queue until it would be admitted, so that it doesn't hold the
+// group's tokens while the Transport's limiters are exhausted or other requests go first; the tokens of the group's limiters are still given back if the request
+// isn't admitted by the Transport's limiters.
+func (t *Transport) wait(ctx context.Context, g *group) error {
+	if g != nil {
+		if err := t.shared().ready(ctx); err != nil {
+			return err
+		}
+		gr, err := t.stageOf(g).wait(ctx)
+		if err != nil {
+			return err
+		}
+		if _, err = t.shared().wait(ctx); err != nil {
+			gr.cancel()
+			return err
+		}
+		return nil
+	}
+	_, err := t.shared().wait(ctx)
+	return err
+}
@@ -0,0 +1,77 @@
+package ratelimithttp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+func TestTransport_Priorities(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		order []string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		order = append(order, r.URL.Query().Get("p"))
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	// the first token arrives long after all the requests are queued
+	limiter := rate.NewLimiter(rate.Every(300*time.Millisecond), 1)
+	limiter.Allow()
+	tr := NewTransport(nil, WithLimiters(limiter))
+	client := &http.Client{Transport: tr}
+
+	var wg sync.WaitGroup
+	send := func(name string, p Priority) {
+		defer wg.Done()
+		req, _ := http.NewRequestWithContext(WithPriority(context.Background(), p), http.MethodGet, srv.URL+"?p="+name, nil)
+		res, err := client.Do(req)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		res.Body.Close()
+	}
+	queued := func(n int) {
+		for tr.sched.len() < n {
+			time.Sleep(time.Millisecond)
+		}
+	}
+	wg.Add(3)
+	go send("low", PriorityLow)
+	queued(1)
+	go send("normal", PriorityNormal)
+	queued(2)
+	go send("high", PriorityHigh)
+	queued(3)
+	wg.Wait()
+
+	if len(order) != 3 || order[0] != "high" || order[1] != "normal" || order[2] != "low" {
+		t.Fatalf("expected requests in priority order, got %v", order)
+	}
+}
+
+func TestTransport_ReservedShare(t *testing.T) {
+	now := time.Now()
+	limiter := rate.NewLimiter(rate.Every(time.Second), 4)
+	tr := NewTransport(nil, WithLimiters(limiter), WithReservedShare(PriorityHigh, 0.5))
+	limiter.ReserveN(now, 2)
+
+	if d := tr.shared().admissionDelay(now, PriorityHigh, nil); d != 0 {
+		t.Fatalf("expected high priority requests to be admitted, got delay %s", d)
+	}
+	if d := tr.shared().admissionDelay(now, PriorityNormal, nil); d != time.Second {
+		t.Fatalf("expected normal priority requests to wait for a token, got delay %s", d)
+	}
+	if d := tr.shared().admissionDelay(now, PriorityHigh, nil); d != 0 {
+		t.Fatal("expected probing not to take tokens")
+	}
+}
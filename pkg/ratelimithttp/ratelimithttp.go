@@ -1,3 +1,5 @@
+// Package ratelimithttp provides rate limited HTTP clients. Transport is an http.RoundTripper that waits for
+// rate limiters before sending each request, and Client is a convenience wrapper of an http.Client using it.
 package ratelimithttp
 
 import (
@@ -5,77 +7,116 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"strings"
 
 	"golang.org/x/time/rate"
 )
 
+// Client is an http.Client whose requests are rate limited by a Transport. The methods without a context parameter
+// use context.Background for their requests; use the Context variants, or Do with a request created with a context,
+// to be able to cancel both the waiting and the request.
+//
+// Each request is charged once, before it is sent: the redirects followed for it aren't charged again, and the
+// waiting doesn't count towards the client's Timeout, which starts once the request is allowed.
 type Client struct {
-	client       *http.Client
-	rateLimiters []*rate.Limiter
+	client    *http.Client
+	transport *Transport
 }
 
-func (c *Client) wait(ctx context.Context) error {
-	for _, rl := range c.rateLimiters {
-		err := rl.Wait(ctx)
-		if err != nil {
-			return err
-		}
-	}
-	return nil
+// Get issues a rate limited GET request to the specified URL. See http.Client.Get.
+func (c *Client) Get(url string) (resp *http.Response, err error) {
+	return c.GetContext(context.Background(), url)
 }
 
-func (c *Client) Get(url string) (resp *http.Response, err error) {
-	err = c.wait(context.Background())
+// GetContext is the same as Get, but the request is done with the given context.
+func (c *Client) GetContext(ctx context.Context, url string) (resp *http.Response, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
-		return
+		return nil, err
 	}
-
-	return c.client.Get(url)
+	return c.Do(req)
 }
 
+// Do sends a rate limited request. See http.Client.Do.
 func (c *Client) Do(req *http.Request) (*http.Response, error) {
-	err := c.wait(req.Context())
-	if err != nil {
+	if err := c.transport.admit(req); err != nil {
+		if req.Body != nil {
+			_ = req.Body.Close()
+		}
 		return nil, err
 	}
-
-	return c.client.Do(req)
+	return c.client.Do(req.WithContext(context.WithValue(req.Context(), admittedKey{}, true)))
 }
 
+// Post issues a rate limited POST request to the specified URL. See http.Client.Post.
 func (c *Client) Post(url string, contentType string, body io.Reader) (resp *http.Response, err error) {
-	err = c.wait(context.Background())
+	return c.PostContext(context.Background(), url, contentType, body)
+}
+
+// PostContext is the same as Post, but the request is done with the given context.
+func (c *Client) PostContext(ctx context.Context, url string, contentType string, body io.Reader) (resp *http.Response, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, body)
 	if err != nil {
-		return
+		return nil, err
 	}
-
-	return c.client.Post(url, contentType, body)
+	req.Header.Set("Content-Type", contentType)
+	return c.Do(req)
 }
 
+// PostForm issues a rate limited POST request to the specified URL, with the data's keys and values URL-encoded
+// as the request body. See http.Client.PostForm.
 func (c *Client) PostForm(url string, data url.Values) (resp *http.Response, err error) {
-	err = c.wait(context.Background())
-	if err != nil {
-		return
-	}
+	return c.PostFormContext(context.Background(), url, data)
+}
 
-	return c.client.PostForm(url, data)
+// PostFormContext is the same as PostForm, but the request is done with the given context.
+func (c *Client) PostFormContext(ctx context.Context, url string, data url.Values) (resp *http.Response, err error) {
+	return c.PostContext(ctx, url, "application/x-www-form-urlencoded", strings.NewReader(data.Encode()))
 }
 
+// Head issues a rate limited HEAD request to the specified URL. See http.Client.Head.
 func (c *Client) Head(url string) (resp *http.Response, err error) {
-	err = c.wait(context.Background())
+	return c.HeadContext(context.Background(), url)
+}
+
+// HeadContext is the same as Head, but the request is done with the given context.
+func (c *Client) HeadContext(ctx context.Context, url string) (resp *http.Response, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
 	if err != nil {
-		return
+		return nil, err
 	}
-
-	return c.client.Head(url)
+	return c.Do(req)
 }
 
+// CloseIdleConnections closes the idle connections of the underlying transport.
 func (c *Client) CloseIdleConnections() {
 	c.client.CloseIdleConnections()
 }
 
+// Transport returns the rate limited transport of the client.
+func (c *Client) Transport() *Transport {
+	return c.transport
+}
+
+// NewClient creates a Client that behaves like the given one, but whose requests are rate limited by the given
+// limiters. The given client isn't modified. If client is nil, http.DefaultClient is used.
 func NewClient(client *http.Client, limiters ...*rate.Limiter) *Client {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return NewClientTransport(client, NewTransport(client.Transport, WithLimiters(limiters...)))
+}
+
+// NewClientTransport creates a Client that behaves like the given one, but sends its requests using t, which
+// replaces the client's transport. The given client isn't modified. If client is nil, http.DefaultClient is used.
+func NewClientTransport(client *http.Client, t *Transport) *Client {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	c := *client
+	c.Transport = t
 	return &Client{
-		client:       client,
-		rateLimiters: limiters,
+		client:    &c,
+		transport: t,
 	}
 }
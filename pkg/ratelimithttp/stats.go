@@ -0,0 +1,134 @@
+package ratelimithttp
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Stats describes the usage of a Transport's limiters since it was created.
+type Stats struct {
+	// Tokens are the tokens currently available in the in-memory limiters, in the order they were given.
+	Tokens []float64
+	// PersistentTokens are the tokens currently available in the persistent limiters, in the order they were given.
+	PersistentTokens []float64
+	// GroupTokens are the tokens currently available in the limiters of each group, by group name.
+	GroupTokens map[string][]float64
+	// Admitted is the number of requests that were allowed by the limiters.
+	Admitted uint64
+	// Denied is the number of requests that failed with a QuotaError.
+	Denied uint64
+	// Canceled is the number of requests whose context was done while waiting for the limiters.
+	Canceled uint64
+	// Queued is the number of requests currently waiting for the limiters, in all groups.
+	Queued int
+	// Waited is the total time the requests spent waiting for the limiters.
+	Waited time.Duration
+}
+
+// RequestStats describes how a request went through the limiters. It is passed to the observer set
+// using WithObserver.
+type RequestStats struct {
+	Request  *http.Request
+	Priority Priority
+	// Waited is the time the request waited for the limiters.
+	Waited time.Duration
+	// Err is the error returned instead of sending the request, if any.
+	Err error
+}
+
+// WithObserver sets a function that is called for every request after it went through the limiters, before
+// it is sent. It is called synchronously, so it must not block.
+func WithObserver(fn func(RequestStats)) Option {
+	return func(t *Transport) {
+		t.observer = fn
+	}
+}
+
+// counters holds the cumulative statistics of a Transport.
+type counters struct {
+	mu       sync.Mutex
+	admitted uint64
+	denied   uint64
+	canceled uint64
+	waited   time.Duration
+}
+
+func (c *counters) record(waited time.Duration, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.waited += waited
+	switch {
+	case err == nil:
+		c.admitted++
+	case errors.Is(err, ErrQuotaExhausted):
+		c.denied++
+	default:
+		c.canceled++
+	}
+}
+
+// tokens returns the number of tokens available in the limiter at now.
+func tokens(l *rate.Limiter, now time.Time) float64 {
+	if l.Limit() == rate.Inf {
+		return math.Inf(1)
+	}
+	return math.Max(0, l.TokensAt(now))
+}
+
+// tokens returns the number of tokens currently available in the limiter.
+func (l *PersistentLimiter) tokens() (float64, error) {
+	if l.limit == rate.Inf {
+		return math.Inf(1), nil
+	}
+	ps, err := l.load()
+	if err != nil {
+		return 0, err
+	}
+	return math.Max(0, l.refill(ps.state, ps.ok, l.now())), nil
+}
+
+// Stats returns the usage statistics of the Transport. The tokens of persistent limiters whose store fails
+// are reported as 0.
+func (t *Transport) Stats() Stats {
+	now := time.Now()
+	s := Stats{
+		Tokens:           make([]float64, len(t.limiters)),
+		PersistentTokens: make([]float64, len(t.persistent)),
+	}
+	for i, l := range t.limiters {
+		s.Tokens[i] = tokens(l, now)
+	}
+	for i, pl := range t.persistent {
+		s.PersistentTokens[i], _ = pl.tokens()
+	}
+	if len(t.groups) > 0 {
+		s.GroupTokens = make(map[string][]float64, len(t.groups))
+		for _, g := range t.groups {
+			gt := make([]float64, len(g.limiters))
+			for i, l := range g.limiters {
+				gt[i] = tokens(l, now)
+			}
+			s.GroupTokens[g.name] = gt
+		}
+	}
+
+	t.counters.mu.Lock()
+	s.Admitted = t.counters.admitted
+	s.Denied = t.counters.denied
+	s.Canceled = t.counters.canceled
+	s.Waited = t.counters.waited
+	t.counters.mu.Unlock()
+
+	s.Queued = t.sched.len()
+	for _, g := range t.groups {
+		s.Queued += g.sched.len()
+	}
+
+	return s
+}
@@ -0,0 +1,100 @@
+package ratelimithttp
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+func TestTransport_Stats(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	var observed []RequestStats
+	tr := NewTransport(nil,
+		WithLimiters(rate.NewLimiter(rate.Every(time.Hour), 3)),
+		WithPersistentLimiters(NewPersistentLimiter(&MemoryStore{}, "key", rate.Every(time.Hour), 10)),
+		WithObserver(func(rs RequestStats) { observed = append(observed, rs) }),
+	)
+	client := &http.Client{Transport: tr}
+	for i := 0; i < 3; i++ {
+		res, err := client.Get(srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	if _, err := client.Do(req); err == nil {
+		t.Fatal("expected the fourth request to be denied")
+	}
+
+	s := tr.Stats()
+	if s.Admitted != 3 || s.Denied != 1 || s.Canceled != 0 || s.Queued != 0 {
+		t.Fatalf("unexpected counters: %+v", s)
+	}
+	if math.Abs(s.Tokens[0]) > 0.01 {
+		t.Fatalf("expected no tokens left, got %v", s.Tokens[0])
+	}
+	if math.Abs(s.PersistentTokens[0]-7) > 0.01 {
+		t.Fatalf("expected 7 persistent tokens left, got %v", s.PersistentTokens[0])
+	}
+	if len(observed) != 4 || observed[3].Err == nil {
+		t.Fatalf("expected 4 observed requests, the last one failed, got %+v", observed)
+	}
+	// probing must not take tokens
+	if s = tr.Stats(); math.Abs(s.PersistentTokens[0]-7) > 0.01 {
+		t.Fatalf("expected the tokens not to change, got %v", s.PersistentTokens[0])
+	}
+}
+
+func TestTokens(t *testing.T) {
+	now := time.Now()
+	l := rate.NewLimiter(2, 10)
+	l.ReserveN(now, 4)
+	if got := tokens(l, now); math.Abs(got-6) > 0.01 {
+		t.Fatalf("expected 6 tokens, got %v", got)
+	}
+	if got := tokens(l, now.Add(time.Second)); math.Abs(got-8) > 0.01 {
+		t.Fatalf("expected 8 tokens after a second, got %v", got)
+	}
+}
+
+func TestTransport_StatsDoesNotTakeTokens(t *testing.T) {
+	limiter := rate.NewLimiter(rate.Every(time.Hour), 5)
+	tr := NewTransport(nil, WithLimiters(limiter))
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				tr.Stats()
+				_ = tr.shared().admissionDelay(time.Now(), PriorityNormal, nil)
+			}
+		}
+	}()
+	allowed := 0
+	for i := 0; i < 1000; i++ {
+		if limiter.Allow() {
+			allowed++
+		}
+	}
+	close(stop)
+	<-done
+
+	if allowed != 5 {
+		t.Fatalf("expected the whole burst of 5 tokens to be usable while probing, got %d", allowed)
+	}
+}
@@ -0,0 +1,287 @@
+package ratelimithttp
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// State is the state of a token bucket: the number of tokens available at the time of the last update.
+// The number of tokens is negative if requests are waiting for tokens.
+type State struct {
+	Tokens  float64   `json:"tokens"`
+	Updated time.Time `json:"updated"`
+}
+
+// Store keeps the state of token buckets, so that it survives process restarts or is shared between processes.
+// Implementations must be safe for concurrent use.
+type Store interface {
+	// Load returns the state stored for the key, without modifying it. The ok result is false if there is no
+	// state stored for the key.
+	Load(key string) (s State, ok bool, err error)
+	// Update atomically replaces the state stored for the key with the one returned by fn. The ok parameter
+	// of fn is false if there is no state stored for the key. fn must not block.
+	Update(key string, fn func(s State, ok bool) State) error
+}
+
+// MemoryStore is a Store that keeps the states in memory. Use it to share a budget between the transports
+// of a process. The zero value is ready to use.
+type MemoryStore struct {
+	mu     sync.Mutex
+	states map[string]State
+}
+
+// Load implements Store.
+func (m *MemoryStore) Load(key string) (State, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, ok := m.states[key]
+	return s, ok, nil
+}
+
+// Update implements Store.
+func (m *MemoryStore) Update(key string, fn func(s State, ok bool) State) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.states == nil {
+		m.states = make(map[string]State)
+	}
+	s, ok := m.states[key]
+	m.states[key] = fn(s, ok)
+	return nil
+}
+
+// FileStore is a Store that keeps the states in a JSON file. Updates write a new file and rename it over the old
+// one, so the file is never left partially written, and they are serialized by locking a separate file next to it,
+// with the ".lock" extension added to its name. The processes on a host that use the same file share the budgets.
+// On platforms without file locking support, only the updates made by the same process are serialized.
+// A file that is empty or can't be decoded is treated as if it had no states, so the buckets start full again.
+type FileStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewFileStore creates a FileStore that uses the file at the given path, which is created if it doesn't exist.
+func NewFileStore(path string) *FileStore {
+	return &FileStore{path: path}
+}
+
+// read returns the states in the file, which are empty if the file doesn't exist or is corrupt.
+func (fs *FileStore) read() (map[string]State, error) {
+	states := make(map[string]State)
+	data, err := ioutil.ReadFile(fs.path)
+	if os.IsNotExist(err) {
+		return states, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(data, &states); err != nil {
+		return make(map[string]State), nil
+	}
+	return states, nil
+}
+
+// Load implements Store. It doesn't lock the file, as updates replace it atomically.
+func (fs *FileStore) Load(key string) (State, bool, error) {
+	states, err := fs.read()
+	if err != nil {
+		return State{}, false, err
+	}
+	s, ok := states[key]
+	return s, ok, nil
+}
+
+// Update implements Store.
+func (fs *FileStore) Update(key string, fn func(s State, ok bool) State) (err error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	lock, err := os.OpenFile(fs.path+".lock", os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := lock.Close(); err == nil {
+			err = cerr
+		}
+	}()
+	if err = lockFile(lock); err != nil {
+		return err
+	}
+	defer func() {
+		if uerr := unlockFile(lock); err == nil {
+			err = uerr
+		}
+	}()
+
+	states, err := fs.read()
+	if err != nil {
+		return err
+	}
+	s, ok := states[key]
+	states[key] = fn(s, ok)
+	data, err := json.Marshal(states)
+	if err != nil {
+		return err
+	}
+	return fs.replace(data)
+}
+
+// replace atomically replaces the contents of the file with data.
+func (fs *FileStore) replace(data []byte) (err error) {
+	f, err := ioutil.TempFile(filepath.Dir(fs.path), filepath.Base(fs.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = f.Close()
+			_ = os.Remove(f.Name())
+		}
+	}()
+	if _, err = f.Write(data); err != nil {
+		return err
+	}
+	if err = f.Sync(); err != nil {
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), fs.path)
+}
+
+// PersistentLimiter is a token bucket rate limiter, like rate.Limiter, whose state is kept in a Store.
+// A new bucket starts full. It is safe for concurrent use.
+type PersistentLimiter struct {
+	store Store
+	key   string
+	limit rate.Limit
+	burst int
+	now   func() time.Time
+}
+
+// NewPersistentLimiter creates a limiter that allows events up to rate r and bursts of at most b events, keeping
+// its state in the store under the given key. Limiters using the same store and key share the budget.
+func NewPersistentLimiter(store Store, key string, r rate.Limit, b int) *PersistentLimiter {
+	return &PersistentLimiter{
+		store: store,
+		key:   key,
+		limit: r,
+		burst: b,
+		now:   time.Now,
+	}
+}
+
+// refill returns the tokens available at now.
+func (l *PersistentLimiter) refill(s State, ok bool, now time.Time) float64 {
+	if !ok {
+		return float64(l.burst)
+	}
+	tokens := s.Tokens
+	if elapsed := now.Sub(s.Updated); elapsed > 0 {
+		tokens += elapsed.Seconds() * float64(l.limit)
+	}
+	return math.Min(tokens, float64(l.burst))
+}
+
+// reserve takes a token and returns the time to wait until it is available.
+func (l *PersistentLimiter) reserve() (delay time.Duration, err error) {
+	if l.limit == rate.Inf {
+		return 0, nil
+	}
+	err = l.store.Update(l.key, func(s State, ok bool) State {
+		now := l.now()
+		tokens := l.refill(s, ok, now) - 1
+		if tokens < 0 {
+			if l.limit <= 0 {
+				delay = time.Duration(math.MaxInt64)
+			} else {
+				delay = time.Duration(-tokens / float64(l.limit) * float64(time.Second))
+			}
+		}
+		return State{Tokens: tokens, Updated: now}
+	})
+	return delay, err
+}
+
+// persistedState is the state of a PersistentLimiter read from its store.
+type persistedState struct {
+	state State
+	ok    bool
+}
+
+// load reads the state of the limiter without modifying it.
+func (l *PersistentLimiter) load() (persistedState, error) {
+	s, ok, err := l.store.Load(l.key)
+	return persistedState{state: s, ok: ok}, err
+}
+
+// delayAt returns the time after which n tokens are available, given the state read by load.
+func (l *PersistentLimiter) delayAt(ps persistedState, now time.Time, n int) time.Duration {
+	if l.limit == rate.Inf {
+		return 0
+	}
+	missing := float64(n) - l.refill(ps.state, ps.ok, now)
+	if missing <= 0 {
+		return 0
+	}
+	if l.limit <= 0 {
+		return time.Duration(math.MaxInt64)
+	}
+	return time.Duration(missing / float64(l.limit) * float64(time.Second))
+}
+
+// release gives back a token taken by reserve.
+func (l *PersistentLimiter) release() error {
+	if l.limit == rate.Inf {
+		return nil
+	}
+	return l.store.Update(l.key, func(s State, ok bool) State {
+		now := l.now()
+		return State{Tokens: math.Min(l.refill(s, ok, now)+1, float64(l.burst)), Updated: now}
+	})
+}
+
+// Wait blocks until the limiter permits an event to happen. It returns an error if the context is canceled or the
+// store fails, and a QuotaError without waiting if the expected wait time exceeds the context's deadline.
+func (l *PersistentLimiter) Wait(ctx context.Context) error {
+	delay, err := l.reserve()
+	if err != nil {
+		return err
+	}
+	if delay == 0 {
+		return nil
+	}
+	if deadline, ok := ctx.Deadline(); ok && delay > time.Until(deadline) {
+		_ = l.release()
+		return &QuotaError{Wait: delay}
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		_ = l.release()
+		return ctx.Err()
+	}
+}
+
+// WithPersistentLimiters adds limiters whose state is kept in a Store to the Transport. They are waited for after
+// the in-memory limiters, and they aren't adjusted by WithAdaptive.
+func WithPersistentLimiters(limiters ...*PersistentLimiter) Option {
+	return func(t *Transport) {
+		t.persistent = append(t.persistent, limiters...)
+	}
+}
@@ -0,0 +1,111 @@
+package ratelimithttp
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+func TestFileStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "quota.json")
+	const n = 20
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// separate stores for the same file behave like separate processes
+			err := NewFileStore(path).Update("key", func(s State, ok bool) State {
+				s.Tokens++
+				return s
+			})
+			if err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	var got State
+	if err := NewFileStore(path).Update("key", func(s State, ok bool) State {
+		got = s
+		return s
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if got.Tokens != n {
+		t.Fatalf("expected %d tokens, got %v", n, got.Tokens)
+	}
+}
+
+func TestFileStore_Corrupt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "quota.json")
+	fs := NewFileStore(path)
+	if _, ok, err := fs.Load("key"); ok || err != nil {
+		t.Fatalf("expected no state in a missing file, got %v, %v", ok, err)
+	}
+
+	for _, data := range []string{"", `{"key":{"tokens":`} {
+		if err := ioutil.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok, err := fs.Load("key"); ok || err != nil {
+			t.Fatalf("%q: expected no state, got %v, %v", data, ok, err)
+		}
+		if err := fs.Update("key", func(s State, ok bool) State {
+			if ok {
+				t.Fatalf("%q: expected no state, got %v", data, s)
+			}
+			return State{Tokens: 3}
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if s, ok, err := fs.Load("key"); !ok || err != nil || s.Tokens != 3 {
+			t.Fatalf("%q: expected the updated state, got %v, %v, %v", data, s, ok, err)
+		}
+	}
+
+	matches, err := filepath.Glob(path + ".*.tmp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 0 {
+		t.Fatalf("temporary files left behind: %v", matches)
+	}
+}
+
+func TestPersistentLimiter(t *testing.T) {
+	now := time.Date(2020, 8, 26, 12, 0, 0, 0, time.UTC)
+	store := &MemoryStore{}
+	newLimiter := func() *PersistentLimiter {
+		l := NewPersistentLimiter(store, "hourly", rate.Every(time.Hour), 2)
+		l.now = func() time.Time { return now }
+		return l
+	}
+
+	l := newLimiter()
+	for i := 0; i < 2; i++ {
+		if err := l.Wait(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// a restarted process continues with the same budget
+	l = newLimiter()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if err := l.Wait(ctx); !errors.Is(err, ErrQuotaExhausted) {
+		t.Fatalf("expected %v, got %v", ErrQuotaExhausted, err)
+	}
+
+	now = now.Add(time.Hour)
+	if err := l.Wait(ctx); err != nil {
+		t.Fatalf("expected a token after an hour, got %v", err)
+	}
+}
@@ -0,0 +1,214 @@
+package ratelimithttp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Transport is an http.RoundTripper that waits for all its rate limiters before passing a request to the underlying
+// RoundTripper. The tokens are reserved on all the limiters at once, and the waiting is bound to the request's
+// context: canceling the request stops the waiting, and a request whose wait would exceed the context's deadline
+// fails immediately with a QuotaError. In both cases the tokens are given back to the limiters.
+// When the limiters are saturated, the requests are sent in the order of their priorities; see WithPriority.
+// Use it as the Transport of any http.Client, or wrap it with other RoundTrippers.
+// It is safe for concurrent use.
+type Transport struct {
+	base       http.RoundTripper
+	limiters   []*rate.Limiter
+	persistent []*PersistentLimiter
+	adaptive   *adaptive
+	// quotaHeaders holds the names of the quota remaining and reset headers set with WithQuotaHeaders,
+	// which are given to adaptive once all the options are applied.
+	quotaHeaders []string
+	shares       map[Priority]float64
+	sched        scheduler
+	counters     counters
+	observer     func(RequestStats)
+	groups       []*group
+}
+
+// ErrQuotaExhausted is matched by the errors returned when a request can't be sent before its context's deadline
+// because of the rate limits. Check for it using errors.Is.
+var ErrQuotaExhausted = errors.New("ratelimithttp: quota exhausted")
+
+// QuotaError is returned instead of waiting for the rate limiters when the wait would exceed the request
+// context's deadline. It matches ErrQuotaExhausted.
+type QuotaError struct {
+	// Wait is the time after which the request would have been sent.
+	Wait time.Duration
+}
+
+func (e *QuotaError) Error() string {
+	if e.Wait == rate.InfDuration {
+		return ErrQuotaExhausted.Error() + ": no request can be sent"
+	}
+	return fmt.Sprintf("%s: next request in %s", ErrQuotaExhausted, e.Wait)
+}
+
+// Is reports whether the target is ErrQuotaExhausted.
+func (e *QuotaError) Is(target error) bool {
+	return target == ErrQuotaExhausted
+}
+
+// Option configures a Transport. Pass options to NewTransport.
+type Option func(*Transport)
+
+// WithLimiters adds rate limiters to the Transport. A request is sent only after it was allowed by all of them.
+func WithLimiters(limiters ...*rate.Limiter) Option {
+	return func(t *Transport) {
+		t.limiters = append(t.limiters, limiters...)
+	}
+}
+
+// NewTransport creates a Transport that sends the requests using base. If base is nil, http.DefaultTransport is used.
+func NewTransport(base http.RoundTripper, opts ...Option) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	t := &Transport{base: base}
+	for _, o := range opts {
+		o(t)
+	}
+	if t.adaptive != nil {
+		if t.quotaHeaders != nil {
+			t.adaptive.remainingHeader, t.adaptive.resetHeader = t.quotaHeaders[0], t.quotaHeaders[1]
+		}
+		t.adaptive.init(t.limiters)
+		for _, g := range t.groups {
+			g.adaptive = t.adaptive.clone()
+			g.adaptive.init(g.limiters)
+		}
+	}
+	return t
+}
+
+// reservation holds the tokens taken from all the limiters of a stage for a single request.
+type reservation struct {
+	limiters   []*rate.Reservation
+	persistent []*PersistentLimiter
+	// delay is the time to wait until all the tokens are available and the stage isn't paused.
+	delay time.Duration
+}
+
+// cancel gives back the reserved tokens, so that other requests can use them.
+func (r *reservation) cancel() {
+	for _, res := range r.limiters {
+		res.Cancel()
+	}
+	for _, pl := range r.persistent {
+		_ = pl.release()
+	}
+}
+
+// reserve takes a token from every limiter of the stage. If a limiter can't ever give a token, the tokens taken
+// from the other limiters are given back.
+func (s *stage) reserve(now time.Time) (*reservation, error) {
+	r := &reservation{}
+	if s.adaptive != nil {
+		r.delay = s.adaptive.pauseLeft()
+	}
+	for _, rl := range s.limiters {
+		res := rl.ReserveN(now, 1)
+		if !res.OK() {
+			r.cancel()
+			return nil, &QuotaError{Wait: rate.InfDuration}
+		}
+		r.limiters = append(r.limiters, res)
+		if d := res.DelayFrom(now); d > r.delay {
+			r.delay = d
+		}
+	}
+	for _, pl := range s.persistent {
+		d, err := pl.reserve()
+		if err != nil {
+			r.cancel()
+			return nil, err
+		}
+		r.persistent = append(r.persistent, pl)
+		if d > r.delay {
+			r.delay = d
+		}
+	}
+	return r, nil
+}
+
+// acquire reserves a token on all the limiters of the stage at once and waits until they are available. If the wait
+// would exceed the context's deadline, the tokens are given back and a QuotaError is returned immediately.
+func (s *stage) acquire(ctx context.Context) (*reservation, error) {
+	r, err := s.reserve(time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if r.delay <= 0 {
+		return r, nil
+	}
+	if deadline, ok := ctx.Deadline(); ok && r.delay > time.Until(deadline) {
+		r.cancel()
+		return nil, &QuotaError{Wait: r.delay}
+	}
+
+	timer := time.NewTimer(r.delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return r, nil
+	case <-ctx.Done():
+		r.cancel()
+		return nil, ctx.Err()
+	}
+}
+
+type admittedKey struct{}
+
+// admit waits for the rate limiters to allow the request, recording the wait.
+func (t *Transport) admit(req *http.Request) error {
+	start := time.Now()
+	err := t.wait(req.Context(), t.groupOf(req))
+	waited := time.Since(start)
+	t.counters.record(waited, err)
+	if t.observer != nil {
+		t.observer(RequestStats{
+			Request:  req,
+			Priority: PriorityFrom(req.Context()),
+			Waited:   waited,
+			Err:      err,
+		})
+	}
+	return err
+}
+
+// RoundTrip waits for the rate limiters and then sends the request using the underlying RoundTripper.
+// Every call is charged, so when the transport is used by an http.Client the redirects it follows are charged too,
+// and the waiting counts towards the client's Timeout. Client doesn't have these issues.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if admitted, _ := req.Context().Value(admittedKey{}).(bool); !admitted {
+		if err := t.admit(req); err != nil {
+			if req.Body != nil {
+				_ = req.Body.Close()
+			}
+			return nil, err
+		}
+	}
+	res, err := t.base.RoundTrip(req)
+	if err == nil {
+		if a := t.adaptiveFor(t.groupOf(req)); a != nil {
+			a.observe(res)
+		}
+	}
+	return res, err
+}
+
+// CloseIdleConnections closes the idle connections of the underlying RoundTripper, if it supports it.
+func (t *Transport) CloseIdleConnections() {
+	type closeIdler interface {
+		CloseIdleConnections()
+	}
+	if ci, ok := t.base.(closeIdler); ok {
+		ci.CloseIdleConnections()
+	}
+}
@@ -0,0 +1,123 @@
+package ratelimithttp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+func TestTransport(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	client := &http.Client{Transport: NewTransport(nil, WithLimiters(rate.NewLimiter(rate.Every(time.Hour), 1)))}
+	res, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	start := time.Now()
+	_, err = client.Do(req)
+	var qe *QuotaError
+	if !errors.Is(err, ErrQuotaExhausted) || !errors.As(err, &qe) {
+		t.Fatalf("expected a quota error, got %v", err)
+	}
+	if qe.Wait < 59*time.Minute {
+		t.Fatalf("expected to wait for about an hour, got %s", qe.Wait)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("expected to fail fast, waited %s", elapsed)
+	}
+}
+
+func TestTransport_ReserveAtomically(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	perSecond := rate.NewLimiter(rate.Every(time.Hour), 1)
+	hourly := rate.NewLimiter(rate.Every(time.Hour), 1)
+	hourly.Allow()
+	client := &http.Client{Transport: NewTransport(nil, WithLimiters(perSecond, hourly))}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	if _, err := client.Do(req); !errors.Is(err, ErrQuotaExhausted) {
+		t.Fatalf("expected %v, got %v", ErrQuotaExhausted, err)
+	}
+	if !perSecond.Allow() {
+		t.Fatal("expected the token of the first limiter to be given back")
+	}
+}
+
+func TestNewClient(t *testing.T) {
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+	}))
+	defer srv.Close()
+
+	c := NewClient(nil, rate.NewLimiter(rate.Inf, 0))
+	for i := 0; i < 3; i++ {
+		res, err := c.Get(srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+	}
+	if requests != 3 {
+		t.Fatalf("expected 3 requests, got %d", requests)
+	}
+	if http.DefaultClient.Transport != nil {
+		t.Fatal("expected the default client not to be modified")
+	}
+}
+
+func TestClient_RedirectsChargedOnce(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			http.Redirect(w, r, "/target", http.StatusFound)
+		}
+	}))
+	defer srv.Close()
+
+	// the limiter allows a single request, so charging the redirect would fail it
+	c := NewClient(&http.Client{Timeout: 10 * time.Millisecond}, rate.NewLimiter(rate.Every(time.Hour), 1))
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	res, err := c.GetContext(ctx, srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.Request.URL.Path != "/target" {
+		t.Fatalf("expected the redirect to be followed, ended at %s", res.Request.URL.Path)
+	}
+
+	if _, err = c.GetContext(ctx, srv.URL); !errors.Is(err, ErrQuotaExhausted) {
+		t.Fatalf("expected %v, got %v", ErrQuotaExhausted, err)
+	}
+}
+
+func TestClient_WaitOutsideTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	c := NewClient(&http.Client{Timeout: time.Second}, rate.NewLimiter(rate.Every(1500*time.Millisecond), 1))
+	for i := 0; i < 2; i++ {
+		res, err := c.Get(srv.URL)
+		if err != nil {
+			t.Fatalf("request %d: %v", i, err)
+		}
+		res.Body.Close()
+	}
+}
@@ -0,0 +1,99 @@
+package lufthansa
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"github.com/tmaxmax/lufthansaapi/pkg/ratelimithttp"
+)
+
+// rateRampUp is the duration over which the request rates are restored after the API signaled that too many
+// requests were made.
+const rateRampUp = time.Minute
+
+// newClient creates the rate limited HTTP client used by the API.
+func (a *API) newClient(reqPerSecond, reqPerHour int) *ratelimithttp.Client {
+	opts := []ratelimithttp.Option{
+		ratelimithttp.WithLimiters(rate.NewLimiter(rate.Every(time.Second), reqPerSecond)),
+		ratelimithttp.WithAdaptive(rateRampUp),
+	}
+	if a.quotaStore != nil {
+		hourly := ratelimithttp.NewPersistentLimiter(a.quotaStore, "lufthansaapi:"+a.clientID+":hour", rate.Every(time.Hour), reqPerHour)
+		opts = append(opts, ratelimithttp.WithPersistentLimiters(hourly))
+	} else {
+		opts = append(opts, ratelimithttp.WithLimiters(rate.NewLimiter(rate.Every(time.Hour), reqPerHour)))
+	}
+	opts = append(opts, a.transportOpts...)
+	return ratelimithttp.NewClientTransport(
+		&http.Client{
+			Timeout: time.Second * 15,
+		},
+		ratelimithttp.NewTransport(nil, opts...),
+	)
+}
+
+// EffectiveRate reports the current request rates, which are lowered for a while after the API responds with
+// 429 Too Many Requests. The limits are those of the per second and the per hour limiters, in this order;
+// the per hour limiter is missing if it is persisted using WithQuotaStore.
+func (a *API) EffectiveRate() ratelimithttp.RateReport {
+	return a.client.Transport().EffectiveRate()
+}
+
+// RateLimitStats returns the usage statistics of the API's request limiters. The tokens are those of the per second
+// and the per hour limiters, in this order; the per hour limiter's tokens are reported as persistent tokens if it is
+// persisted using WithQuotaStore. Use ratelimithttp.WithObserver with WithRateLimitOptions to be notified
+// about every request.
+func (a *API) RateLimitStats() ratelimithttp.Stats {
+	return a.client.Transport().Stats()
+}
+
+// WithQuotaStore makes the API keep the state of its hourly request limiter in the given store, so that the hourly
+// quota isn't reset when the process restarts. Processes sharing the store and the client ID share the quota;
+// use ratelimithttp.NewFileStore to share it between the processes on a host.
+func WithQuotaStore(store ratelimithttp.Store) Option {
+	return func(a *API) {
+		a.quotaStore = store
+	}
+}
+
+// WithRateLimitOptions passes options to the rate limited transport of the API. For example, use
+// ratelimithttp.WithReservedShare to keep a part of the request budget for requests whose context was given
+// a high priority using ratelimithttp.WithPriority, so that they aren't delayed by bulk work:
+//
+//	api, err := lufthansa.NewAPI(ctx, id, secret, 5, 1000, lufthansa.WithRateLimitOptions(
+//		ratelimithttp.WithReservedShare(ratelimithttp.PriorityHigh, 0.2),
+//	))
+//	...
+//	countries := api.FetchCountries(params)
+//	for countries.Next(ratelimithttp.WithPriority(ctx, ratelimithttp.PriorityLow)) {
+//		// bulk work
+//	}
+func WithRateLimitOptions(opts ...ratelimithttp.Option) Option {
+	return func(a *API) {
+		a.transportOpts = append(a.transportOpts, opts...)
+	}
+}
+
+// WithEndpointLimits limits the requests to the endpoints whose path, relative to the API root, starts with one of
+// the given prefixes (for example "/operations" or "/mds-references"), in addition to the API-wide limits. Use it
+// when the plan has separate quotas for different products: the requests of each group wait separately for the
+// group's limits, so a burst of requests to one product doesn't delay the requests to the others, and a 429 Too Many
+// Requests response for one product slows down only that product's requests. The name identifies the group in
+// RateLimitStats. A request belongs to the first group that matches it.
+func WithEndpointLimits(name string, reqPerSecond, reqPerHour int, pathPrefixes ...string) Option {
+	root, _ := url.Parse(fetchAPI)
+	paths := make([]string, len(pathPrefixes))
+	for i, prefix := range pathPrefixes {
+		paths[i] = root.Path + "/" + strings.Trim(prefix, "/")
+	}
+	return WithRateLimitOptions(ratelimithttp.WithGroup(
+		name,
+		ratelimithttp.PathPrefix(paths...),
+		rate.NewLimiter(rate.Every(time.Second), reqPerSecond),
+		rate.NewLimiter(rate.Every(time.Hour), reqPerHour),
+	))
+}
@@ -2,6 +2,7 @@ package lufthansa
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"io"
 	"strconv"
@@ -85,6 +86,23 @@ func (rn *referenceNames) Copy() referenceNames {
 	return r
 }
 
+// codeList is a list of codes that the API sends as a single string when it has only one element.
+type codeList []string
+
+func (cl *codeList) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*cl = codeList{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	*cl = list
+	return nil
+}
+
 // reference meta types
 type (
 	metaLinks         map[metaKey]string
@@ -143,7 +161,7 @@ func (m *meta) hasSelf() bool {
 	return ok && m.checkVersion()
 }
 
-func (m *meta) fetch(a *API, ctx context.Context, rel metaKey) (bool, io.ReadCloser, error) {
+func (m *meta) fetch(a *API, ctx context.Context, rel metaKey, v *validators) (bool, io.ReadCloser, error) {
 	if !m.checkVersion() {
 		return false, nil, ErrInvalidMeta
 	}
@@ -151,7 +169,8 @@ func (m *meta) fetch(a *API, ctx context.Context, rel metaKey) (bool, io.ReadClo
 	if !ok {
 		return false, nil, nil
 	}
-	fetched, err := a.fetch(ctx, url)
+	// the current set is always requested from the API, so a stale cached copy isn't returned
+	fetched, err := a.fetchValidated(ctx, url, v, rel == metaKeySelf)
 	if err != nil {
 		return false, nil, err
 	}
@@ -159,15 +178,16 @@ func (m *meta) fetch(a *API, ctx context.Context, rel metaKey) (bool, io.ReadClo
 }
 
 type iterator struct {
-	ref       referenceAPIResponse
-	api       *API
-	err       error
-	mu        sync.RWMutex
-	firstNext sync.Once
+	ref        referenceAPIResponse
+	api        *API
+	validators validators
+	err        error
+	mu         sync.RWMutex
+	firstNext  sync.Once
 }
 
 func (i *iterator) fetchFromMeta(ctx context.Context, rel metaKey) error {
-	ok, fetched, err := i.ref.metadata().fetch(i.api, ctx, rel)
+	ok, fetched, err := i.ref.metadata().fetch(i.api, ctx, rel, &i.validators)
 	if !ok {
 		if err != nil {
 			return err
@@ -182,6 +202,10 @@ func (i *iterator) iterate(ctx context.Context, rel metaKey) bool {
 	defer i.mu.Unlock()
 
 	i.err = i.fetchFromMeta(ctx, rel)
+	if i.err == errNotModified {
+		i.err = nil
+		return true
+	}
 	if i.err == errMissingMetaKey {
 		if rel == metaKeyNext || rel == metaKeyPrevious {
 			*i = iterator{
@@ -217,7 +241,11 @@ func (i *iterator) Next(ctx context.Context) bool {
 		i.mu.Lock()
 		defer i.mu.Unlock()
 
-		fetched, err := i.api.fetch(ctx, i.ref.metadata().links[metaKeyNext])
+		fetched, err := i.api.fetchValidated(ctx, i.ref.metadata().links[metaKeyNext], &i.validators, false)
+		if err == errNotModified {
+			iterated = true
+			return
+		}
 		if err != nil {
 			i.err = err
 			return
@@ -253,6 +281,8 @@ func (i *iterator) HasSelf() bool {
 
 // Self refetches the last countries resource fetched, overwriting the current set.
 // If you want a copy of the current Countries struct, use the Countries.Copy method instead.
+// The request is conditional on the ETag and Last-Modified validators of the previous response, if the API sent
+// any: when the set didn't change, the API doesn't send it again and the current set is kept.
 func (i *iterator) Self(ctx context.Context) {
 	i.iterate(ctx, metaKeySelf)
 }
@@ -284,8 +314,9 @@ func (i *iterator) copy(newAPI *API) iterator {
 		newAPI = i.api
 	}
 	return iterator{
-		ref: i.ref,
-		api: newAPI,
+		ref:        i.ref,
+		api:        newAPI,
+		validators: i.validators,
 	}
 }
 
@@ -307,6 +338,16 @@ type RefParams struct {
 	Offset int
 }
 
+// langCode returns the base language code of the tag, as expected by the API's lang parameter.
+// English is used if the base language can't be determined.
+func langCode(tag language.Tag) string {
+	b, c := tag.Base()
+	if c == language.No {
+		b, _ = language.English.Base()
+	}
+	return b.String()
+}
+
 func writeAmp(sb *strings.Builder, mustWrite *bool) {
 	if *mustWrite {
 		sb.WriteByte('&')
@@ -328,13 +369,9 @@ func (p *RefParams) ToURL() string {
 		sb.WriteString(p.code)
 	}
 	if p.Lang != nil {
-		b, c := p.Lang.Base()
-		if c == language.No {
-			b, _ = language.English.Base()
-		}
 		sb.WriteString("?lang=")
 		mustWriteAmp = true
-		sb.WriteString(b.String())
+		sb.WriteString(langCode(*p.Lang))
 	}
 	if p.Limit != 0 {
 		writeAmp(&sb, &mustWriteAmp)
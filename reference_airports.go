@@ -2,13 +2,55 @@ package lufthansa
 
 import (
 	"context"
+	"fmt"
 	"io"
+	"math"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/tmaxmax/lufthansaapi/internal/util"
 	"golang.org/x/text/language"
 )
 
+// LocationType is the kind of location an Airport record describes. Besides airports, the airports reference
+// also contains railway and bus stations that are part of the Lufthansa network.
+type LocationType uint8
+
+// The location types sent by the airports reference. LocationUnknown is used for values not known by this package.
+const (
+	LocationUnknown LocationType = iota
+	LocationAirport
+	LocationRailwayStation
+	LocationBusStation
+)
+
+var locationTypeNames = [...]string{"", "Airport", "RailwayStation", "BusStation"}
+
+func (lt LocationType) String() string {
+	if int(lt) >= len(locationTypeNames) {
+		return ""
+	}
+	return locationTypeNames[lt]
+}
+
+// MarshalText implements encoding.TextMarshaler, returning the name used by the API.
+func (lt LocationType) MarshalText() ([]byte, error) {
+	return []byte(lt.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler. Names not known by this package result in LocationUnknown.
+func (lt *LocationType) UnmarshalText(data []byte) error {
+	*lt = LocationUnknown
+	for i := range locationTypeNames {
+		if i > 0 && locationTypeNames[i] == string(data) {
+			*lt = LocationType(i)
+			break
+		}
+	}
+	return nil
+}
+
 type (
 	airportPosition struct {
 		Latitude  float64 `xml:"Coordinate>Latitude" json:"Coordinate.Latitude"`
@@ -19,7 +61,7 @@ type (
 		Position     airportPosition          `xml:"Position" json:"Position"`
 		CityCode     string                   `xml:"CityCode" json:"CityCode"`
 		CountryCode  string                   `xml:"CountryCode" json:"CountryCode"`
-		LocationType string                   `xml:"LocationType" json:"LocationType"`
+		LocationType LocationType             `xml:"LocationType" json:"LocationType"`
 		Names        []referenceNameUnmarshal `xml:"Names>Name" json:"Names.Name"`
 		UTCOffset    string                   `xml:"UtcOffset" json:"UtcOffset"`
 		TimeZoneID   string                   `xml:"TimeZoneId" json:"TimeZoneId"`
@@ -31,29 +73,57 @@ type (
 
 	Airport struct {
 		AirportCode  string
-		Position     airportPosition
+		Position     Coordinate
 		CityCode     string
 		CountryCode  string
-		LocationType string
+		LocationType LocationType
 		Names        referenceNames
 		UTCOffset    string
 		TimeZoneID   string
 	}
 	Airports struct {
-		Airports []Airport
-		meta     meta
+		Airports      []Airport
+		meta          meta
+		locationTypes []LocationType
 		iterator
 	}
+
+	// AirportsOption configures the set returned by API.FetchAirports.
+	AirportsOption func(*Airports)
 )
 
+// KeepLocationTypes makes the airports set keep only the records of the given location types. The filtering
+// is done on each fetched page, so pages may contain fewer records than RefParams.Limit.
+func KeepLocationTypes(types ...LocationType) AirportsOption {
+	return func(as *Airports) {
+		as.locationTypes = append([]LocationType(nil), types...)
+	}
+}
+
+func (as *Airports) keeps(lt LocationType) bool {
+	if len(as.locationTypes) == 0 {
+		return true
+	}
+	for _, t := range as.locationTypes {
+		if t == lt {
+			return true
+		}
+	}
+	return false
+}
+
 func (as *Airports) decode(r io.ReadCloser) error {
 	au := &airportsUnmarshal{}
 	if err := util.Decode(r, au); err != nil {
 		return err
 	}
-	as.Airports = make([]Airport, len(au.Airports))
-	for i := range as.Airports {
-		as.Airports[i].make(&au.Airports[i])
+	as.Airports = make([]Airport, 0, len(au.Airports))
+	for i := range au.Airports {
+		if !as.keeps(au.Airports[i].LocationType) {
+			continue
+		}
+		as.Airports = append(as.Airports, Airport{})
+		as.Airports[len(as.Airports)-1].make(&au.Airports[i])
 	}
 	as.meta.make(&au.Meta)
 	return nil
@@ -65,8 +135,9 @@ func (as *Airports) metadata() *meta {
 
 func (as *Airports) Copy(newAPI *API) *Airports {
 	r := &Airports{
-		Airports: make([]Airport, 0, len(as.Airports)),
-		iterator: as.iterator.copy(newAPI),
+		Airports:      make([]Airport, 0, len(as.Airports)),
+		locationTypes: as.locationTypes,
+		iterator:      as.iterator.copy(newAPI),
 	}
 	for i := range as.Airports {
 		r.Airports = append(r.Airports, *as.Airports[i].Copy())
@@ -89,7 +160,7 @@ func (a *Airport) decode(r io.ReadCloser) error {
 
 func (a *Airport) make(au *airportUnmarshal) {
 	a.AirportCode = au.AirportCode
-	a.Position = au.Position
+	a.Position = Coordinate(au.Position)
 	a.CityCode = au.CityCode
 	a.CountryCode = au.CountryCode
 	a.LocationType = au.LocationType
@@ -111,11 +182,85 @@ func (a *Airport) Copy() *Airport {
 	}
 }
 
+// Location returns the airport's time zone. The IANA time zone given by TimeZoneID is used if it is available
+// on this system, otherwise a fixed zone is built from UTCOffset.
+func (a *Airport) Location() (*time.Location, error) {
+	if a.TimeZoneID != "" {
+		if loc, err := time.LoadLocation(a.TimeZoneID); err == nil {
+			return loc, nil
+		}
+	}
+	offset, err := parseUTCOffset(a.UTCOffset)
+	if err != nil {
+		return nil, err
+	}
+	sign, abs := '+', offset
+	if offset < 0 {
+		sign, abs = '-', -offset
+	}
+	return time.FixedZone(fmt.Sprintf("UTC%c%02d:%02d", sign, abs/3600, abs%3600/60), offset), nil
+}
+
+// parseUTCOffset parses offsets formatted as "+01:00", "+0100", "+1" or "-3.5", returning them in seconds.
+func parseUTCOffset(s string) (int, error) {
+	invalid := fmt.Errorf("lufthansa: airport: invalid UTC offset %q", s)
+
+	str := strings.TrimSpace(s)
+	if str == "" {
+		return 0, invalid
+	}
+	sign := 1
+	switch str[0] {
+	case '-':
+		sign = -1
+		fallthrough
+	case '+':
+		str = str[1:]
+	}
+	var hours, minutes float64
+	var err error
+	switch {
+	case strings.Contains(str, ":"):
+		parts := strings.SplitN(str, ":", 2)
+		if hours, err = strconv.ParseFloat(parts[0], 64); err == nil {
+			minutes, err = strconv.ParseFloat(parts[1], 64)
+		}
+	case len(str) == 4 && !strings.Contains(str, "."):
+		if hours, err = strconv.ParseFloat(str[:2], 64); err == nil {
+			minutes, err = strconv.ParseFloat(str[2:], 64)
+		}
+	default:
+		hours, err = strconv.ParseFloat(str, 64)
+	}
+	if err != nil || hours < 0 || hours > 14 || minutes < 0 || minutes >= 60 {
+		return 0, invalid
+	}
+	return sign * int(math.Round(hours*3600+minutes*60)), nil
+}
+
+// DistanceTo returns the great-circle distance between the two airports in kilometres.
+func (a *Airport) DistanceTo(o *Airport) float64 {
+	return a.Position.DistanceTo(o.Position)
+}
+
+// BearingTo returns the initial bearing from this airport to the other one, in degrees clockwise from north.
+func (a *Airport) BearingTo(o *Airport) float64 {
+	return a.Position.BearingTo(o.Position)
+}
+
+// IsAirport reports whether the record describes an airport, as opposed to a railway or bus station.
+func (a *Airport) IsAirport() bool {
+	return a.LocationType == LocationAirport
+}
+
 func (a *Airport) String() string {
 	return util.Stringer.Stringify(a, "")
 }
 
-func (a *API) FetchAirports(p *RefParams, LHOperated bool) *Airports {
+// FetchAirports requests from the airports reference. If LHOperated is true, only the locations served by Lufthansa
+// are returned. If you want to fetch a single airport, use FetchAirport instead.
+// The API request doesn't happen here, you must call the Next method before.
+func (a *API) FetchAirports(p *RefParams, LHOperated bool, opts ...AirportsOption) *Airports {
 	url := mdsReferenceAPI + "/airports/" + p.ToURL()
 	if LHOperated {
 		if strings.Contains(url, "?") {
@@ -134,6 +279,9 @@ func (a *API) FetchAirports(p *RefParams, LHOperated bool) *Airports {
 			api: a,
 		},
 	}
+	for _, o := range opts {
+		o(as)
+	}
 	as.iterator.ref = as
 	return as
 }
@@ -1,7 +1,9 @@
 package lufthansa_test
 
 import (
+	"math"
 	"testing"
+	"time"
 
 	lufthansa "github.com/tmaxmax/lufthansaapi"
 	"golang.org/x/text/language"
@@ -28,6 +30,37 @@ func TestAPI_FetchAirports(t *testing.T) {
 	}
 }
 
+func TestAirports_Self(t *testing.T) {
+	ar := api.FetchAirports(&lufthansa.RefParams{Lang: &language.English, Limit: 5}, false)
+	if !ar.Next(ctx) {
+		t.Fatal(ar.Error())
+	}
+	codes := make([]string, len(ar.Airports))
+	for i, a := range ar.Airports {
+		codes[i] = a.AirportCode
+	}
+	// the first refetch bypasses the cache, which served the first set, and the second one is conditional
+	// on the validators of the first one
+	hits := api.CacheStats().Hits
+	for i := 0; i < 2; i++ {
+		ar.Self(ctx)
+		if ar.Error() != nil {
+			t.Fatal(ar.Error())
+		}
+	}
+	if h := api.CacheStats().Hits; h != hits {
+		t.Fatalf("expected refetches not to be served from the cache, got %d hits", h-hits)
+	}
+	if len(ar.Airports) != len(codes) {
+		t.Fatalf("expected %d airports after refetch, got %d", len(codes), len(ar.Airports))
+	}
+	for i, a := range ar.Airports {
+		if a.AirportCode != codes[i] {
+			t.Fatalf("expected airport %s at position %d, got %s", codes[i], i, a.AirportCode)
+		}
+	}
+}
+
 func TestAPI_FetchAirport(t *testing.T) {
 	airport, err := api.FetchAirport(ctx, "TXL", nil)
 	if err != nil {
@@ -35,3 +68,76 @@ func TestAPI_FetchAirport(t *testing.T) {
 	}
 	t.Logf("%s", airport)
 }
+
+func TestAirport_Location(t *testing.T) {
+	tests := []struct {
+		airport lufthansa.Airport
+		offset  int
+	}{
+		{lufthansa.Airport{TimeZoneID: "UTC", UTCOffset: "+05:00"}, 0},
+		{lufthansa.Airport{TimeZoneID: "Nowhere/Invalid", UTCOffset: "+05:30"}, 5*3600 + 30*60},
+		{lufthansa.Airport{UTCOffset: "-0300"}, -3 * 3600},
+		{lufthansa.Airport{UTCOffset: "1.0"}, 3600},
+	}
+	for _, test := range tests {
+		loc, err := test.airport.Location()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, offset := time.Date(2020, time.January, 1, 0, 0, 0, 0, loc).Zone(); offset != test.offset {
+			t.Fatalf("%+v: expected offset %d, got %d", test.airport, test.offset, offset)
+		}
+	}
+	if _, err := (&lufthansa.Airport{UTCOffset: "abc"}).Location(); err == nil {
+		t.Fatal("expected error for invalid offset")
+	}
+}
+
+func TestAirport_DistanceTo(t *testing.T) {
+	fra := &lufthansa.Airport{Position: lufthansa.Coordinate{Latitude: 50.0333, Longitude: 8.5706}}
+	jfk := &lufthansa.Airport{Position: lufthansa.Coordinate{Latitude: 40.6398, Longitude: -73.7789}}
+
+	if d := fra.DistanceTo(jfk); math.Abs(d-6200) > 20 {
+		t.Fatalf("expected FRA-JFK distance of about 6200km, got %f", d)
+	}
+	if b := fra.BearingTo(jfk); math.Abs(b-294.4) > 1 {
+		t.Fatalf("expected FRA-JFK initial bearing of about 294.4 degrees, got %f", b)
+	}
+	if d := fra.DistanceTo(fra); d != 0 {
+		t.Fatalf("expected zero distance, got %f", d)
+	}
+}
+
+func TestLocationType_Text(t *testing.T) {
+	for _, lt := range []lufthansa.LocationType{lufthansa.LocationAirport, lufthansa.LocationRailwayStation, lufthansa.LocationBusStation} {
+		text, err := lt.MarshalText()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got lufthansa.LocationType
+		if err = got.UnmarshalText(text); err != nil {
+			t.Fatal(err)
+		}
+		if got != lt {
+			t.Fatalf("expected %s, got %s", lt, got)
+		}
+	}
+	var unknown lufthansa.LocationType
+	if err := unknown.UnmarshalText([]byte("Heliport")); err != nil || unknown != lufthansa.LocationUnknown {
+		t.Fatalf("expected unknown location type, got %v (%v)", unknown, err)
+	}
+}
+
+func TestAPI_FetchAirports_KeepLocationTypes(t *testing.T) {
+	ar := api.FetchAirports(&lufthansa.RefParams{Limit: 100}, false, lufthansa.KeepLocationTypes(lufthansa.LocationRailwayStation))
+	for i := 0; ar.Next(ctx) && i < 2; i++ {
+		for _, a := range ar.Airports {
+			if a.LocationType != lufthansa.LocationRailwayStation || a.IsAirport() {
+				t.Fatalf("unexpected location type %s for %s", a.LocationType, a.AirportCode)
+			}
+		}
+	}
+	if ar.Error() != nil {
+		t.Fatal(ar.Error())
+	}
+}
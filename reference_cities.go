@@ -2,6 +2,7 @@ package lufthansa
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 
@@ -10,11 +11,18 @@ import (
 	"golang.org/x/text/language"
 )
 
+// resolveConcurrency is the maximum number of concurrent requests done when resolving references to other resources.
+const resolveConcurrency = 4
+
+// ErrNoAPI is returned when a resource that wasn't fetched through an API is asked to fetch related resources.
+var ErrNoAPI = errors.New("lufthansa: resource is not bound to an API")
+
 type (
 	CityUnmarshal struct {
 		CityCode    string                   `xml:"CityCode" json:"CityCode"`
 		CountryCode string                   `xml:"CountryCode" json:"CountryCode"`
 		Names       []referenceNameUnmarshal `xml:"Names>Name" json:"Names.Name"`
+		Airports    codeList                 `xml:"Airports>AirportCode" json:"Airports.AirportCode"`
 	}
 	CitiesUnmarshal struct {
 		Cities []CityUnmarshal `xml:"Cities>City" json:"CityResource.Cities.City"`
@@ -25,6 +33,8 @@ type (
 		CityCode    string
 		CountryCode string
 		Names       referenceNames
+		Airports    []string
+		api         *API
 	}
 	Cities struct {
 		Cities []City
@@ -41,6 +51,7 @@ func (cs *Cities) decode(r io.ReadCloser) error {
 	cs.Cities = make([]City, len(cu.Cities))
 	for i := range cs.Cities {
 		cs.Cities[i].make(&cu.Cities[i])
+		cs.Cities[i].api = cs.iterator.api
 	}
 	cs.meta.make(cu.Meta)
 	return nil
@@ -57,6 +68,7 @@ func (cs *Cities) Copy(newAPI *API) *Cities {
 	}
 	for i := range cs.Cities {
 		r.Cities = append(r.Cities, *cs.Cities[i].Copy())
+		r.Cities[i].api = r.iterator.api
 	}
 	return r
 }
@@ -69,6 +81,7 @@ func (c *City) make(cu *CityUnmarshal) {
 	c.CityCode = cu.CityCode
 	c.CountryCode = cu.CountryCode
 	c.Names.make(cu.Names)
+	c.Airports = append([]string(nil), cu.Airports...)
 }
 
 func (c *City) decode(r io.ReadCloser) error {
@@ -85,7 +98,27 @@ func (c *City) Copy() *City {
 		CityCode:    c.CityCode,
 		CountryCode: c.CountryCode,
 		Names:       c.Names.Copy(),
+		Airports:    append([]string(nil), c.Airports...),
+		api:         c.api,
+	}
+}
+
+// ResolveAirports fetches the airports serving the city, in the order of City.Airports, using API.FetchAirport.
+// At most resolveConcurrency requests are done at a time. The city must have been obtained from an API,
+// otherwise ErrNoAPI is returned.
+func (c *City) ResolveAirports(ctx context.Context) ([]*Airport, error) {
+	if c.api == nil {
+		return nil, ErrNoAPI
+	}
+	airports := make([]*Airport, len(c.Airports))
+	err := util.Parallel(ctx, len(c.Airports), resolveConcurrency, func(ctx context.Context, i int) (err error) {
+		airports[i], err = c.api.FetchAirport(ctx, c.Airports[i], nil)
+		return err
+	})
+	if err != nil {
+		return nil, err
 	}
+	return airports, nil
 }
 
 func (c *City) String() string {
@@ -114,6 +147,6 @@ func (a *API) FetchCity(ctx context.Context, cityCode string, lang *language.Tag
 		return nil, err
 	}
 
-	c := &City{}
+	c := &City{api: a}
 	return c, c.decode(fetched)
 }
@@ -39,3 +39,29 @@ func TestAPI_FetchCity(t *testing.T) {
 	}
 	t.Logf("%s", city)
 }
+
+func TestCity_ResolveAirports(t *testing.T) {
+	city, err := api.FetchCity(ctx, "BER", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	airports, err := city.ResolveAirports(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(airports) != len(city.Airports) {
+		t.Fatalf("expected %d airports, got %d", len(city.Airports), len(airports))
+	}
+	for i, a := range airports {
+		if a.AirportCode != city.Airports[i] {
+			t.Fatalf("expected airport %s, got %s", city.Airports[i], a.AirportCode)
+		}
+	}
+}
+
+func TestCity_ResolveAirports_NoAPI(t *testing.T) {
+	city := &lufthansa.City{CityCode: "BER", Airports: []string{"BER"}}
+	if _, err := city.ResolveAirports(ctx); err != lufthansa.ErrNoAPI {
+		t.Fatalf("expected ErrNoAPI, got %v", err)
+	}
+}
@@ -0,0 +1,195 @@
+package lufthansa
+
+import (
+	"context"
+	"sync"
+
+	"github.com/tmaxmax/lufthansaapi/internal/util"
+)
+
+// Resolver navigates between the reference resources: it finds the City and Country of an Airport and the Country
+// of a City. Every resource is fetched at most once, in all the available languages, and then memoized; concurrent
+// lookups of the same code share a single request. Obtain it with API.Resolver. It is safe for concurrent use.
+//
+// The returned values are copies, so they can be freely modified by the caller.
+type Resolver struct {
+	api *API
+
+	mu        sync.RWMutex
+	countries map[string]*Country
+	cities    map[string]*City
+	airports  map[string]*Airport
+}
+
+// Resolver returns the API's resolver. The same resolver is returned on every call, so the memoized resources
+// are shared by all its users.
+func (a *API) Resolver() *Resolver {
+	a.copyCheck()
+	a.resolverOnce.Do(func() {
+		a.resolver = &Resolver{
+			api:       a,
+			countries: make(map[string]*Country),
+			cities:    make(map[string]*City),
+			airports:  make(map[string]*Airport),
+		}
+	})
+	return a.resolver
+}
+
+// Reset discards all the memoized resources.
+func (r *Resolver) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.countries = make(map[string]*Country)
+	r.cities = make(map[string]*City)
+	r.airports = make(map[string]*Airport)
+}
+
+// resolve returns the memoized value using lookup, or fetches it using fetch and memoizes it using store.
+// Concurrent fetches of the same resource share a single request, as all the API's fetches do.
+func (r *Resolver) resolve(ctx context.Context, lookup func() (interface{}, bool), fetch func(context.Context) (interface{}, error), store func(interface{})) (interface{}, error) {
+	r.mu.RLock()
+	v, ok := lookup()
+	r.mu.RUnlock()
+	if ok {
+		return v, nil
+	}
+
+	v, err := fetch(ctx)
+	if err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	store(v)
+	r.mu.Unlock()
+	return v, nil
+}
+
+// Country returns the country with the given code.
+func (r *Resolver) Country(ctx context.Context, code string) (*Country, error) {
+	v, err := r.resolve(ctx, func() (interface{}, bool) {
+		c, ok := r.countries[code]
+		return c, ok
+	}, func(ctx context.Context) (interface{}, error) {
+		return r.api.FetchCountry(ctx, code, nil)
+	}, func(v interface{}) {
+		r.countries[code] = v.(*Country)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.(*Country).Copy(), nil
+}
+
+// City returns the city with the given code.
+func (r *Resolver) City(ctx context.Context, code string) (*City, error) {
+	v, err := r.resolve(ctx, func() (interface{}, bool) {
+		c, ok := r.cities[code]
+		return c, ok
+	}, func(ctx context.Context) (interface{}, error) {
+		return r.api.FetchCity(ctx, code, nil)
+	}, func(v interface{}) {
+		r.cities[code] = v.(*City)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.(*City).Copy(), nil
+}
+
+// Airport returns the airport with the given code.
+func (r *Resolver) Airport(ctx context.Context, code string) (*Airport, error) {
+	v, err := r.resolve(ctx, func() (interface{}, bool) {
+		a, ok := r.airports[code]
+		return a, ok
+	}, func(ctx context.Context) (interface{}, error) {
+		return r.api.FetchAirport(ctx, code, nil)
+	}, func(v interface{}) {
+		r.airports[code] = v.(*Airport)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.(*Airport).Copy(), nil
+}
+
+// Countries returns the countries with the given codes, in the same order. Duplicate codes are fetched once,
+// and at most resolveConcurrency requests are done at a time.
+func (r *Resolver) Countries(ctx context.Context, codes ...string) ([]*Country, error) {
+	unique := dedupe(codes)
+	fetched := make(map[string]*Country, len(unique))
+	var mu sync.Mutex
+	err := util.Parallel(ctx, len(unique), resolveConcurrency, func(ctx context.Context, i int) error {
+		c, err := r.Country(ctx, unique[i])
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		fetched[unique[i]] = c
+		mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]*Country, len(codes))
+	for i, code := range codes {
+		ret[i] = fetched[code]
+	}
+	return ret, nil
+}
+
+// Cities returns the cities with the given codes, in the same order. Duplicate codes are fetched once,
+// and at most resolveConcurrency requests are done at a time.
+func (r *Resolver) Cities(ctx context.Context, codes ...string) ([]*City, error) {
+	unique := dedupe(codes)
+	fetched := make(map[string]*City, len(unique))
+	var mu sync.Mutex
+	err := util.Parallel(ctx, len(unique), resolveConcurrency, func(ctx context.Context, i int) error {
+		c, err := r.City(ctx, unique[i])
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		fetched[unique[i]] = c
+		mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]*City, len(codes))
+	for i, code := range codes {
+		ret[i] = fetched[code]
+	}
+	return ret, nil
+}
+
+// AirportCountry returns the country the airport is located in.
+func (r *Resolver) AirportCountry(ctx context.Context, a *Airport) (*Country, error) {
+	return r.Country(ctx, a.CountryCode)
+}
+
+// AirportCity returns the city the airport serves.
+func (r *Resolver) AirportCity(ctx context.Context, a *Airport) (*City, error) {
+	return r.City(ctx, a.CityCode)
+}
+
+// CityCountry returns the country the city is located in.
+func (r *Resolver) CityCountry(ctx context.Context, c *City) (*Country, error) {
+	return r.Country(ctx, c.CountryCode)
+}
+
+func dedupe(codes []string) []string {
+	seen := make(map[string]struct{}, len(codes))
+	ret := make([]string, 0, len(codes))
+	for _, c := range codes {
+		if _, ok := seen[c]; ok {
+			continue
+		}
+		seen[c] = struct{}{}
+		ret = append(ret, c)
+	}
+	return ret
+}
@@ -0,0 +1,76 @@
+package lufthansa_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+)
+
+func TestResolver(t *testing.T) {
+	r := api.Resolver()
+	if r != api.Resolver() {
+		t.Fatal("expected the same resolver on every call")
+	}
+	airport, err := r.Airport(ctx, "TXL")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			country, err := r.AirportCountry(ctx, airport)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if country.CountryCode != airport.CountryCode {
+				t.Errorf("expected country %s, got %s", airport.CountryCode, country.CountryCode)
+			}
+		}()
+	}
+	wg.Wait()
+
+	city, err := r.AirportCity(ctx, airport)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = r.CityCountry(ctx, city); err != nil {
+		t.Fatal(err)
+	}
+
+	countries, err := r.Countries(ctx, "DE", "RO", "DE")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(countries) != 3 || countries[0].CountryCode != "DE" || countries[1].CountryCode != "RO" || countries[2].CountryCode != "DE" {
+		t.Fatalf("unexpected countries %v", countries)
+	}
+}
+
+func TestResolver_FirstCallerCanceled(t *testing.T) {
+	r := api.Resolver()
+	r.Reset()
+
+	canceled, cancel := context.WithCancel(ctx)
+	errc := make(chan error, 1)
+	go func() {
+		_, err := r.Country(canceled, "FR")
+		errc <- err
+	}()
+	cancel()
+
+	country, err := r.Country(ctx, "FR")
+	if err != nil {
+		t.Fatalf("expected the second caller to retry after the first was canceled, got %v", err)
+	}
+	if country.CountryCode != "FR" {
+		t.Fatalf("expected country FR, got %s", country.CountryCode)
+	}
+	if err := <-errc; err != nil && !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the first caller to succeed or be canceled, got %v", err)
+	}
+}